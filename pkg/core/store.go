@@ -642,12 +642,15 @@ func MergeLabels(origin []*metapb.StoreLabel, labels []*metapb.StoreLabel) []*me
 type StoresInfo struct {
 	syncutil.RWMutex
 	stores map[uint64]*StoreInfo
+	// removedListeners are notified after a store is deleted, keyed by name.
+	removedListeners map[string]func(*StoreInfo)
 }
 
 // NewStoresInfo create a StoresInfo with map of storeID to StoreInfo
 func NewStoresInfo() *StoresInfo {
 	return &StoresInfo{
-		stores: make(map[uint64]*StoreInfo),
+		stores:           make(map[uint64]*StoreInfo),
+		removedListeners: make(map[string]func(*StoreInfo)),
 	}
 }
 
@@ -874,8 +877,33 @@ func (s *StoresInfo) ResetStoreLimit(storeID uint64, limitType storelimit.Type,
 // DeleteStore deletes tombstone record form store
 func (s *StoresInfo) DeleteStore(store *StoreInfo) {
 	s.Lock()
-	defer s.Unlock()
 	delete(s.stores, store.GetID())
+	listeners := make([]func(*StoreInfo), 0, len(s.removedListeners))
+	for _, listener := range s.removedListeners {
+		listeners = append(listeners, listener)
+	}
+	s.Unlock()
+	// The listeners are called without the lock, so that they can access the
+	// stores.
+	for _, listener := range listeners {
+		listener(store)
+	}
+}
+
+// RegisterStoreRemovedListener registers a listener with the given name, which
+// will be called after a store is deleted. The listener with the same name will
+// be replaced.
+func (s *StoresInfo) RegisterStoreRemovedListener(name string, listener func(*StoreInfo)) {
+	s.Lock()
+	defer s.Unlock()
+	s.removedListeners[name] = listener
+}
+
+// UnregisterStoreRemovedListener unregisters the listener with the given name.
+func (s *StoresInfo) UnregisterStoreRemovedListener(name string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.removedListeners, name)
 }
 
 // UpdateStoreStatus updates the information of the store.
@@ -563,6 +563,19 @@ func (mc *Cluster) UpdateStoreLeaderWeight(storeID uint64, weight float64) {
 	mc.PutStore(newStore)
 }
 
+// SetStoreWeight sets up a store's leader/region balance weight, and saves them.
+func (mc *Cluster) SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) error {
+	store := mc.GetStore(storeID)
+	if store == nil {
+		return errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+	if err := mc.SaveStoreWeight(storeID, leaderWeight, regionWeight); err != nil {
+		return err
+	}
+	mc.PutStore(store.Clone(core.SetLeaderWeight(leaderWeight), core.SetRegionWeight(regionWeight)))
+	return nil
+}
+
 // SetStoreEvictLeader set store whether evict leader.
 func (mc *Cluster) SetStoreEvictLeader(storeID uint64, enableEvictLeader bool) {
 	store := mc.GetStore(storeID)
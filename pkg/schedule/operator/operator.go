@@ -412,6 +412,16 @@ func (o *Operator) GetPriorityLevel() constant.PriorityLevel {
 	return o.level
 }
 
+// SetTimeout sets the timeout of the operator.
+func (o *Operator) SetTimeout(timeout time.Duration) {
+	o.timeout = timeout
+}
+
+// GetTimeout gets the timeout of the operator.
+func (o *Operator) GetTimeout() time.Duration {
+	return o.timeout
+}
+
 // UnfinishedInfluence calculates the store difference which unfinished operator steps make.
 func (o *Operator) UnfinishedInfluence(opInfluence OpInfluence, region *core.RegionInfo) {
 	for step := atomic.LoadInt32(&o.currentStep); int(step) < len(o.steps); step++ {
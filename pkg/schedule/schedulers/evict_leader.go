@@ -15,8 +15,10 @@
 package schedulers
 
 import (
+	"bytes"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/errors"
@@ -163,6 +165,10 @@ func (conf *evictLeaderSchedulerConfig) getKeyRangesByID(id uint64) []core.KeyRa
 	return nil
 }
 
+func (*evictLeaderSchedulerConfig) getEvictLeaderOptions(sche.SchedulerCluster) evictLeaderOptions {
+	return evictLeaderOptions{}
+}
+
 type evictLeaderScheduler struct {
 	*BaseScheduler
 	conf    *evictLeaderSchedulerConfig
@@ -274,12 +280,50 @@ func uniqueAppendOperator(dst []*operator.Operator, src ...*operator.Operator) [
 type evictLeaderStoresConf interface {
 	getStores() []uint64
 	getKeyRangesByID(id uint64) []core.KeyRange
+	// getEvictLeaderOptions returns the options of evicting leaders, it's
+	// called once per batch.
+	getEvictLeaderOptions(cluster sche.SchedulerCluster) evictLeaderOptions
+}
+
+// evictLeaderOptions customizes the eviction of leaders, the zero value evicts
+// the leaders in the default way.
+type evictLeaderOptions struct {
+	// scatter spreads the evicted leaders of one batch across the target stores,
+	// to avoid moving the leaders of adjacent key ranges onto the same store.
+	scatter bool
+	// operatorTimeout is the timeout of the operators, 0 means the default one.
+	operatorTimeout time.Duration
+	// criticalRegionFilter protects the leaders of the critical regions, such
+	// as the meta regions, from being evicted casually. nil means there is no
+	// critical region.
+	criticalRegionFilter filter.RegionFilter
+	// evictCriticalRegionLast evicts the leaders of the critical regions after
+	// the others, otherwise they are never evicted.
+	evictCriticalRegionLast bool
+	// excludedTargets keeps the evicted leaders away from some stores, e.g., the
+	// stores which are slow as well, so that the problem is not just moved to
+	// another store.
+	excludedTargets map[uint64]struct{}
+	// skippedRegionFilter skips some regions, e.g., the regions which already
+	// have the in-flight operators, so that no duplicate operator is issued.
+	// nil means no region is skipped.
+	skippedRegionFilter filter.RegionFilter
+	// priorityKeyRanges are the key ranges evicted first, e.g., the
+	// latency-critical tables. Empty means no priority.
+	priorityKeyRanges []core.KeyRange
 }
 
 func scheduleEvictLeaderBatch(name, typ string, cluster sche.SchedulerCluster, conf evictLeaderStoresConf, batchSize int) []*operator.Operator {
 	var ops []*operator.Operator
+	opts := conf.getEvictLeaderOptions(cluster)
+	// assigned records the number of leaders transferred to each target store
+	// in this batch, it's nil if the leaders are not required to be scattered.
+	var assigned map[uint64]int
+	if opts.scatter {
+		assigned = make(map[uint64]int)
+	}
 	for i := 0; i < batchSize; i++ {
-		once := scheduleEvictLeaderOnce(name, typ, cluster, conf)
+		once := scheduleEvictLeaderOnce(name, typ, cluster, conf, &opts, assigned)
 		// no more regions
 		if len(once) == 0 {
 			break
@@ -293,7 +337,7 @@ func scheduleEvictLeaderBatch(name, typ string, cluster sche.SchedulerCluster, c
 	return ops
 }
 
-func scheduleEvictLeaderOnce(name, typ string, cluster sche.SchedulerCluster, conf evictLeaderStoresConf) []*operator.Operator {
+func scheduleEvictLeaderOnce(name, typ string, cluster sche.SchedulerCluster, conf evictLeaderStoresConf, opts *evictLeaderOptions, assigned map[uint64]int) []*operator.Operator {
 	stores := conf.getStores()
 	ops := make([]*operator.Operator, 0, len(stores))
 	for _, storeID := range stores {
@@ -302,16 +346,33 @@ func scheduleEvictLeaderOnce(name, typ string, cluster sche.SchedulerCluster, co
 			continue
 		}
 		var filters []filter.Filter
-		pendingFilter := filter.NewRegionPendingFilter()
-		downFilter := filter.NewRegionDownFilter()
-		region := filter.SelectOneRegion(cluster.RandLeaderRegions(storeID, ranges), nil, pendingFilter, downFilter)
+		var skippedFilters, regionFilters []filter.RegionFilter
+		if opts.skippedRegionFilter != nil {
+			skippedFilters = append(skippedFilters, opts.skippedRegionFilter)
+		}
+		regionFilters = append(regionFilters, skippedFilters...)
+		evictCriticalLast := false
+		if opts.criticalRegionFilter != nil {
+			regionFilters = append(regionFilters, opts.criticalRegionFilter)
+			evictCriticalLast = opts.evictCriticalRegionLast
+		}
+		var region *core.RegionInfo
+		var healthy bool
+		if priorityRanges := intersectKeyRanges(ranges, opts.priorityKeyRanges); len(priorityRanges) > 0 {
+			region, healthy = selectEvictLeaderRegion(cluster, storeID, priorityRanges, regionFilters...)
+		}
 		if region == nil {
-			// try to pick unhealthy region
-			region = filter.SelectOneRegion(cluster.RandLeaderRegions(storeID, ranges), nil)
-			if region == nil {
-				evictLeaderNoLeaderCounter.Inc()
-				continue
-			}
+			region, healthy = selectEvictLeaderRegion(cluster, storeID, ranges, regionFilters...)
+		}
+		if region == nil && evictCriticalLast {
+			// Only the leaders of the critical regions are left, evict them at last.
+			region, healthy = selectEvictLeaderRegion(cluster, storeID, ranges, skippedFilters...)
+		}
+		if region == nil {
+			evictLeaderNoLeaderCounter.Inc()
+			continue
+		}
+		if !healthy {
 			evictLeaderPickUnhealthyCounter.Inc()
 			unhealthyPeerStores := make(map[uint64]struct{})
 			for _, peer := range region.GetDownPeers() {
@@ -322,6 +383,9 @@ func scheduleEvictLeaderOnce(name, typ string, cluster sche.SchedulerCluster, co
 			}
 			filters = append(filters, filter.NewExcludedFilter(name, nil, unhealthyPeerStores))
 		}
+		if len(opts.excludedTargets) > 0 {
+			filters = append(filters, filter.NewExcludedFilter(name, nil, opts.excludedTargets))
+		}
 
 		filters = append(filters, &filter.StoreStateFilter{ActionScope: name, TransferLeader: true, OperatorLevel: constant.Urgent})
 		candidates := filter.NewCandidates(cluster.GetFollowerStores(region)).
@@ -338,18 +402,66 @@ func scheduleEvictLeaderOnce(name, typ string, cluster sche.SchedulerCluster, co
 		for _, t := range targets {
 			targetIDs = append(targetIDs, t.GetID())
 		}
+		if assigned != nil {
+			// Pick the target with the fewest leaders assigned in this batch, and
+			// do not let TiKV choose another one.
+			target = candidates.Shuffle().PickTheTopStore(func(a, b *core.StoreInfo) int {
+				return assigned[a.GetID()] - assigned[b.GetID()]
+			}, true)
+			targetIDs = []uint64{target.GetID()}
+		}
 		op, err := operator.CreateTransferLeaderOperator(typ, cluster, region, target.GetID(), targetIDs, operator.OpLeader)
 		if err != nil {
 			log.Debug("fail to create evict leader operator", errs.ZapError(err))
 			continue
 		}
+		if assigned != nil {
+			assigned[target.GetID()]++
+		}
 		op.SetPriorityLevel(constant.Urgent)
+		if opts.operatorTimeout > 0 {
+			op.SetTimeout(opts.operatorTimeout)
+		}
 		op.Counters = append(op.Counters, evictLeaderNewOperatorCounter)
 		ops = append(ops, op)
 	}
 	return ops
 }
 
+// intersectKeyRanges returns the parts of the key ranges `b` within the key
+// ranges `a`, the empty end key means the end of the whole key space.
+func intersectKeyRanges(a, b []core.KeyRange) []core.KeyRange {
+	var ranges []core.KeyRange
+	for _, ra := range a {
+		for _, rb := range b {
+			start := ra.StartKey
+			if bytes.Compare(rb.StartKey, start) > 0 {
+				start = rb.StartKey
+			}
+			end := ra.EndKey
+			if len(end) == 0 || (len(rb.EndKey) > 0 && bytes.Compare(rb.EndKey, end) < 0) {
+				end = rb.EndKey
+			}
+			if len(end) > 0 && bytes.Compare(start, end) >= 0 {
+				continue
+			}
+			ranges = append(ranges, core.KeyRange{StartKey: start, EndKey: end})
+		}
+	}
+	return ranges
+}
+
+// selectEvictLeaderRegion picks a region whose leader is on the store to evict,
+// the healthy regions are preferred.
+func selectEvictLeaderRegion(cluster sche.SchedulerCluster, storeID uint64, ranges []core.KeyRange, regionFilters ...filter.RegionFilter) (region *core.RegionInfo, healthy bool) {
+	healthyFilters := append([]filter.RegionFilter{filter.NewRegionPendingFilter(), filter.NewRegionDownFilter()}, regionFilters...)
+	if region = filter.SelectOneRegion(cluster.RandLeaderRegions(storeID, ranges), nil, healthyFilters...); region != nil {
+		return region, true
+	}
+	// try to pick unhealthy region
+	return filter.SelectOneRegion(cluster.RandLeaderRegions(storeID, ranges), nil, regionFilters...), false
+}
+
 type evictLeaderHandler struct {
 	rd     *render.Render
 	config *evictLeaderSchedulerConfig
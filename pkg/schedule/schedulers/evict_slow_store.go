@@ -99,6 +99,10 @@ func (conf *evictSlowStoreSchedulerConfig) getKeyRangesByID(id uint64) []core.Ke
 	return []core.KeyRange{core.NewKeyRange("", "")}
 }
 
+func (*evictSlowStoreSchedulerConfig) getEvictLeaderOptions(sche.SchedulerCluster) evictLeaderOptions {
+	return evictLeaderOptions{}
+}
+
 func (conf *evictSlowStoreSchedulerConfig) evictStore() uint64 {
 	if len(conf.getStores()) == 0 {
 		return 0
@@ -15,21 +15,37 @@
 package schedulers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/pkg/core"
+	"github.com/tikv/pd/pkg/core/constant"
+	"github.com/tikv/pd/pkg/errs"
 	sche "github.com/tikv/pd/pkg/schedule/core"
+	"github.com/tikv/pd/pkg/schedule/filter"
 	"github.com/tikv/pd/pkg/schedule/operator"
 	"github.com/tikv/pd/pkg/schedule/plan"
 	"github.com/tikv/pd/pkg/storage/endpoint"
 	"github.com/tikv/pd/pkg/utils/apiutil"
+	"github.com/tikv/pd/pkg/utils/logutil"
+	"github.com/tikv/pd/pkg/utils/reflectutil"
 	"github.com/tikv/pd/pkg/utils/syncutil"
+	"github.com/tikv/pd/pkg/utils/typeutil"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 )
@@ -42,47 +58,1013 @@ const (
 )
 
 const (
-	alterEpsilon               = 1e-9
-	minReCheckDurationGap      = 120 // default gap for re-check the slow node, unit: s
-	defaultRecoveryDurationGap = 600 // default gap for recovery, unit: s.
+	// slowTrendLogLevelNormal logs the routine state transitions at debug level.
+	slowTrendLogLevelNormal = "normal"
+	// slowTrendLogLevelVerbose logs the routine state transitions at info level.
+	slowTrendLogLevelVerbose = "verbose"
+)
+
+const (
+	// slowTrendDetectionModeCrossSectional compares the store with other stores.
+	slowTrendDetectionModeCrossSectional = "cross-sectional"
+	// slowTrendDetectionModeLongitudinal compares the store with its own baseline.
+	slowTrendDetectionModeLongitudinal = "longitudinal"
+)
+
+const (
+	// slowTrendComparisonPairwise confirms the candidate if it's slower than
+	// most of the other stores.
+	slowTrendComparisonPairwise = "pairwise"
+	// slowTrendComparisonMedian confirms the candidate if it's much slower than
+	// the median of the other stores, which is more robust to outliers.
+	slowTrendComparisonMedian = "median"
+)
+
+const (
+	// quorumDenominatorAll counts all the stores toward the total in the ratio
+	// and quorum math, including the ones not eligible, e.g., the preparing
+	// stores during scaling out.
+	quorumDenominatorAll = "all"
+	// quorumDenominatorEligible only counts the eligible stores.
+	quorumDenominatorEligible = "eligible"
+)
+
+const (
+	// criticalRegionPolicySkip never evicts the leaders of the critical regions.
+	criticalRegionPolicySkip = "skip"
+	// criticalRegionPolicyLast evicts the leaders of the critical regions after
+	// the other leaders are evicted.
+	criticalRegionPolicyLast = "last"
+)
+
+const (
+	// persistFailurePolicyRetry keeps the eviction decisions which fail to be
+	// persisted, and persists them again in the following schedules.
+	persistFailurePolicyRetry = "retry"
+	// persistFailurePolicyRollback rolls back the eviction decisions which fail
+	// to be persisted.
+	persistFailurePolicyRollback = "rollback"
+)
+
+const (
+	// offlineEvictedStorePolicyKeep keeps evicting the offline store until it's
+	// removed.
+	offlineEvictedStorePolicyKeep = "keep"
+	// offlineEvictedStorePolicyStop stops managing the offline store, and lets
+	// the decommission proceed.
+	offlineEvictedStorePolicyStop = "stop"
+)
+
+const (
+	// slowTrendProfileConservative captures the slow store only if it affects
+	// many stores obviously, and recovers it soon.
+	slowTrendProfileConservative = "conservative"
+	// slowTrendProfileBalanced is the same as the default thresholds.
+	slowTrendProfileBalanced = "balanced"
+	// slowTrendProfileAggressive captures the slow store even if it affects few
+	// stores, and recovers it only if it's faster than most stores for long.
+	slowTrendProfileAggressive = "aggressive"
+)
+
+// slowTrendProfile is the set of the thresholds which a profile expands to.
+type slowTrendProfile struct {
+	AffectedStoreRatioThreshold float64
+	RecoveryQuorumRatio         float64
+	RecoveryDurationGap         uint64
+	ComparisonEpsilon           float64
+}
+
+var slowTrendProfiles = map[string]slowTrendProfile{
+	slowTrendProfileConservative: {
+		AffectedStoreRatioThreshold: 0.5,
+		RecoveryQuorumRatio:         0.34,
+		RecoveryDurationGap:         300,
+		ComparisonEpsilon:           1e-3,
+	},
+	slowTrendProfileBalanced: {
+		AffectedStoreRatioThreshold: defaultAffectedStoreRatioThreshold,
+		RecoveryQuorumRatio:         0,
+		RecoveryDurationGap:         defaultRecoveryDurationGap,
+		ComparisonEpsilon:           alterEpsilon,
+	},
+	slowTrendProfileAggressive: {
+		AffectedStoreRatioThreshold: 0.1,
+		RecoveryQuorumRatio:         0.8,
+		RecoveryDurationGap:         1800,
+		ComparisonEpsilon:           1e-12,
+	},
+}
+
+// evictCleanupReason is the reason of cleaning up the evicted store.
+type evictCleanupReason string
+
+const (
+	evictCleanupReasonRecovered evictCleanupReason = "recovered"
+	evictCleanupReasonRemoved   evictCleanupReason = "removed"
+	evictCleanupReasonOffline   evictCleanupReason = "offline"
+	// evictCleanupReasonBelowMinStores means the stores are removed to fewer
+	// than `MinStoresForEvicting` during the eviction.
+	evictCleanupReasonBelowMinStores evictCleanupReason = "below-min-stores"
+	// evictCleanupReasonCleaned means the scheduler itself is removed.
+	evictCleanupReasonCleaned evictCleanupReason = "cleaned"
+)
+
+const (
+	// slowTrendStateIdle means there is neither a candidate nor an evicted store.
+	slowTrendStateIdle = "idle"
+	// slowTrendStateCandidatePending means the candidate is waiting to be confirmed.
+	slowTrendStateCandidatePending = "candidate-pending"
+	// slowTrendStateEvicting means the leaders of the evicted store are being evicted.
+	slowTrendStateEvicting = "evicting"
+)
+
+const (
+	alterEpsilon                = 1e-9
+	minReCheckDurationGap       = 120  // default gap for re-check the slow node, unit: s
+	defaultRecoveryDurationGap  = 600  // default gap for recovery, unit: s.
+	defaultEvictionSLA          = 1800 // default SLA of the eviction, unit: s.
+	defaultEvictionBudgetWindow = 3600 // default window of the eviction budget, unit: s.
+	defaultWriteStallDuration   = 60   // default duration of the write stall to be regarded as slow, unit: s.
+	defaultConfirmationSamples  = 1    // default number of samples to confirm the candidate.
+	defaultConfirmationInterval = 60   // default interval between the samples of the candidate, unit: s.
+	defaultFlapWindow           = 3600 // default window to detect the flapping stores, unit: s.
+	defaultFlapHoldDuration     = 3600 // default duration to hold a flapping store evicted, unit: s.
+	defaultEvictRampStep        = 1    // default step to increase the batch size of evicting the leaders.
+	defaultEvictRampInterval    = 60   // default interval to increase the batch size of evicting the leaders, unit: s.
+	defaultMinStoresForEvicting = 3    // default minimum number of the stores to evict the slow store.
+	// default ratio of `CauseValue` to its baseline to regard the store as regressed.
+	defaultBaselineRegressionRatio = 3.0
+	// smoothing factor of the rolling baseline of `CauseValue`.
+	baselineSmoothingFactor = 0.1
+	defaultConfidenceDecay  = 1.0
+	// ratio of `CauseValue` to the median of others to regard the store as slower.
+	slowerThanMedianRatio = 2.0
+	// the minimum ratio of the leader weight of the soft evicted store to its
+	// original one.
+	minSoftEvictLeaderWeightRatio = 0.1
+	// hostLabelKey is the key of the label of the host where the store is.
+	hostLabelKey = "host"
+	// tailLatencyRecordKey is the key of the tail latency in the op latencies
+	// reported by the store.
+	tailLatencyRecordKey = "tail-latency"
+)
+
+var (
+	// WithLabelValues is a heavy operation, define variable to avoid call it every time.
+	slowTrendCandidateCanceledCounter  = storeSlowTrendCandidateResultCounter.WithLabelValues("canceled")
+	slowTrendCandidateEvictedCounter   = storeSlowTrendCandidateResultCounter.WithLabelValues("evicted")
+	evictSlowTrendPausedCounter        = schedulerCounter.WithLabelValues(EvictSlowTrendName, "paused")
+	evictSlowTrendMaintenanceCounter   = schedulerCounter.WithLabelValues(EvictSlowTrendName, "paused_maintenance_window")
+	evictSlowTrendContradictoryCounter = schedulerCounter.WithLabelValues(EvictSlowTrendName, "skip_capture_contradictory_config")
+	evictSlowTrendFlappingCounter      = schedulerCounter.WithLabelValues(EvictSlowTrendName, "flapping_detected")
+	slowTrendWebhookSentCounter        = storeSlowTrendWebhookEventCounter.WithLabelValues("sent")
+	slowTrendWebhookFailedCounter      = storeSlowTrendWebhookEventCounter.WithLabelValues("failed")
+	slowTrendWebhookDroppedCounter     = storeSlowTrendWebhookEventCounter.WithLabelValues("dropped")
+
+	criticalRegionStatus = plan.NewStatus(plan.StatusRegionLabelReject)
+	inflightRegionStatus = plan.NewStatus(plan.StatusRegionNotMatchRule)
+
+	// Every way a candidate can leave the pending state has its own counter.
+	slowTrendCandidateExitTooFasterCounter      = storeSlowTrendCandidateExitCounter.WithLabelValues("too_faster")
+	slowTrendCandidateExitNotConfirmedCounter   = storeSlowTrendCandidateExitCounter.WithLabelValues("not_confirmed")
+	slowTrendCandidateExitRemovedCounter        = storeSlowTrendCandidateExitCounter.WithLabelValues("removed")
+	slowTrendCandidateExitAlreadyEvictedCounter = storeSlowTrendCandidateExitCounter.WithLabelValues("already_evicted")
+	slowTrendCandidateExitPrepareFailedCounter  = storeSlowTrendCandidateExitCounter.WithLabelValues("prepare_failed")
+	slowTrendCandidateExitEvictedCounter        = storeSlowTrendCandidateExitCounter.WithLabelValues("evicted")
+
+	// The disk-slow and network-slow stores are either captured or only alerted.
+	slowTrendDiskSlowCaptureCounter    = storeSlowTrendSlowKindCounter.WithLabelValues("disk", "capture")
+	slowTrendDiskSlowAlertCounter      = storeSlowTrendSlowKindCounter.WithLabelValues("disk", "alert")
+	slowTrendNetworkSlowCaptureCounter = storeSlowTrendSlowKindCounter.WithLabelValues("network", "capture")
+	slowTrendNetworkSlowAlertCounter   = storeSlowTrendSlowKindCounter.WithLabelValues("network", "alert")
 )
 
 type slowCandidate struct {
-	storeID   uint64
-	captureTS time.Time
-	recoverTS time.Time
+	StoreID   uint64    `json:"store-id"`
+	CaptureTS time.Time `json:"capture-ts"`
+	RecoverTS time.Time `json:"recover-ts"`
+	// Number of the samples which observed the candidate as slow.
+	Samples uint64 `json:"samples"`
+	// Timestamp of the last sample.
+	SampleTS time.Time `json:"sample-ts"`
+}
+
+// slowTrendState is a snapshot of the states maintained by the scheduler.
+type slowTrendState struct {
+	EvictCandidate     slowCandidate
+	LastEvictCandidate slowCandidate
+	EvictedStores      []uint64
+}
+
+// SlowTrendStatus is the state of the evict-slow-trend scheduler contributed to
+// the cluster status.
+type SlowTrendStatus struct {
+	State                 string            `json:"state"`
+	EvictedStores         []uint64          `json:"evicted-stores"`
+	EvictedReasons        map[uint64]string `json:"evicted-reasons,omitempty"`
+	Candidate             uint64            `json:"candidate"`
+	DetectionMode         string            `json:"detection-mode"`
+	ComparisonMethod      string            `json:"comparison-method"`
+	RecoveryDurationGap   uint64            `json:"recovery-duration"`
+	ConfirmationSamples   uint64            `json:"confirmation-samples"`
+	MaxEvictionsPerWindow uint64            `json:"max-evictions-per-window"`
+}
+
+const (
+	// slowTrendStoreStatusIdle means the store is neither the candidate nor
+	// evicted.
+	slowTrendStoreStatusIdle = "idle"
+	// slowTrendStoreStatusCandidate means the store is the pending candidate.
+	slowTrendStoreStatusCandidate = "candidate"
+	// slowTrendStoreStatusEvicted means the leaders of the store are evicted.
+	slowTrendStoreStatusEvicted = "evicted"
+)
+
+// SlowTrendStoreStatus is the eviction status of a store.
+type SlowTrendStoreStatus struct {
+	// State is one of "idle", "candidate" and "evicted".
+	State string `json:"state"`
+	// ElapsedSecs is the duration since the store was captured as the
+	// candidate or evicted, unit: s. It's 0 for the idle store.
+	ElapsedSecs uint64 `json:"elapsed-secs"`
+	// RecoveryProgress is how close the evicted store is to the recovery gap,
+	// 1.0 means the gap has been reached. It's 0 for the store not evicted.
+	RecoveryProgress float64 `json:"recovery-progress"`
+}
+
+// slowTrendScanResult is the result of scanning one store in a tick.
+type slowTrendScanResult struct {
+	StoreID     uint64  `json:"store-id"`
+	CauseRate   float64 `json:"cause-rate"`
+	ResultRate  float64 `json:"result-rate"`
+	CauseValue  float64 `json:"cause-value"`
+	ResultValue float64 `json:"result-value"`
+	// Whether the slow trend matches the pattern of a slow store.
+	Matched bool `json:"matched"`
+	// The number of other stores which the store is slower than.
+	SlowerThanCount int `json:"slower-than-count"`
+	// The number of other stores which the store is not obviously slower than.
+	FasterThanCount int `json:"faster-than-count"`
+}
+
+// slowTrendHistorySize is the maximum number of the evictions kept in the
+// history.
+const slowTrendHistorySize = 32
+
+// slowTrendSnapshot is the slow trend reported by a store at a moment.
+type slowTrendSnapshot struct {
+	TS          time.Time `json:"ts"`
+	CauseRate   float64   `json:"cause-rate"`
+	ResultRate  float64   `json:"result-rate"`
+	CauseValue  float64   `json:"cause-value"`
+	ResultValue float64   `json:"result-value"`
+}
+
+func newSlowTrendSnapshot(store *core.StoreInfo, ts time.Time) *slowTrendSnapshot {
+	if store == nil || store.GetSlowTrend() == nil {
+		return nil
+	}
+	slowTrend := store.GetSlowTrend()
+	return &slowTrendSnapshot{
+		TS:          ts,
+		CauseRate:   slowTrend.CauseRate,
+		ResultRate:  slowTrend.ResultRate,
+		CauseValue:  slowTrend.CauseValue,
+		ResultValue: slowTrend.ResultValue,
+	}
+}
+
+// slowTrendHistoryEntry is the record of an eviction. The slow trends at the
+// moments of capturing, evicting and recovering are kept, so that they can be
+// compared to check whether the thresholds are appropriate.
+type slowTrendHistoryEntry struct {
+	StoreID uint64             `json:"store-id"`
+	Capture *slowTrendSnapshot `json:"capture,omitempty"`
+	Evict   *slowTrendSnapshot `json:"evict,omitempty"`
+	Recover *slowTrendSnapshot `json:"recover,omitempty"`
+	// Reason is why the eviction ended, empty means it's still ongoing.
+	Reason string `json:"reason,omitempty"`
+}
+
+// slowTrendScan is the snapshot of the stores scanned in the latest tick.
+type slowTrendScan struct {
+	ScanTS time.Time             `json:"scan-ts"`
+	Stores []slowTrendScanResult `json:"stores"`
 }
 
 type evictSlowTrendSchedulerConfig struct {
 	syncutil.RWMutex
 	cluster *core.BasicCluster
 	storage endpoint.ConfigStorage
-	// Candidate for eviction in current tick.
-	evictCandidate slowCandidate
-	// Last chosen candidate for eviction.
-	lastEvictCandidate slowCandidate
+	// now returns the current time, it can be replaced to mock the clock. All
+	// the timestamps of the states are taken from it, so that a recorded
+	// timeline can be replayed by replacing it.
+	now func() time.Time
+	// The time when the write stall of each store was first observed, it's
+	// only kept in memory.
+	writeStallSince map[uint64]time.Time
+	// logger is used to log the state transitions, nil means the global logger.
+	logger *zap.Logger
+	// The rolling baseline of `CauseValue` of each store, it's only kept in
+	// memory.
+	causeValueBaselines map[uint64]float64
+	// The number of consecutive ticks in which the evicted store looks fast.
+	recoveryFastTicks uint64
+	// The time when the evicted store began to look fast.
+	recoveryFastSince time.Time
+	// The snapshot of the latest scan, it's only kept in memory for debugging.
+	lastScan slowTrendScan
+	// The history entry of the candidate, it's added to the history once the
+	// candidate is evicted.
+	pendingHistory *slowTrendHistoryEntry
+	// The recent evictions, it's only kept in memory for debugging.
+	history []slowTrendHistoryEntry
+	// Whether the in-memory state fails to be persisted and waits for retrying.
+	persistPending bool
+	// The timestamps of the evictions of each store within `FlapWindow`, it's
+	// only kept in memory.
+	evictionHistory map[uint64][]time.Time
+	// The time when the candidate of each store was canceled latest, it's only
+	// kept in memory.
+	candidateCanceledAt map[uint64]time.Time
+	// The time when each store was seen reporting the slow trend first since
+	// it started, it's only kept in memory.
+	trendFirstSeen map[uint64]trendFirstSeen
+	// removeSchedulerCb removes the scheduler itself, it's nil if the scheduler
+	// is not created by the coordinator.
+	removeSchedulerCb func(string) error
+	// Whether the scheduler has removed itself for being idle.
+	autoDisabled bool
+	// The regions with the in-flight operators found on prepare, e.g., issued
+	// by the previous leader, they are skipped until the operators finish.
+	inflightRegions map[uint64]struct{}
+	// hasOperator checks whether the region still has an operator, it's set
+	// on prepare.
+	hasOperator func(regionID uint64) bool
 	// Duration gap for recovering the candidate, unit: s.
 	RecoveryDurationGap uint64 `json:"recovery-duration"`
+	// The evicted store must keep looking fast for the duration it had been
+	// slow multiplied by this factor before recovering. 0 means disabled.
+	RecoveryGapScalingFactor float64 `json:"recovery-gap-scaling-factor"`
+	// The cap of the scaled recovery gap, unit: s. 0 means no cap.
+	MaxRecoveryDurationGap uint64 `json:"max-recovery-duration"`
+	// Whether to use the slow score of stores to pick the candidate when
+	// there are multiple stores matching the slow trend pattern.
+	UseSlowScore bool `json:"use-slow-score"`
+	// Whether to fall back to the cause-only pattern for the stores which
+	// do not report the result fields of the slow trend.
+	ResultFieldsOptional bool `json:"result-fields-optional"`
+	// The ceiling of the median `CauseValue` of all stores. If the median
+	// exceeds it, the whole cluster is regarded as slow and no candidate will
+	// be captured. 0 means no limit.
+	ClusterSlowCauseValueCeiling float64 `json:"cluster-slow-cause-value-ceiling"`
+	// Whether to transfer leaders back to the store once it's recovered.
+	RebalanceOnRecover bool `json:"rebalance-on-recover"`
+	// The maximum number of evictions within `EvictionBudgetWindow`, 0 means no limit.
+	MaxEvictionsPerWindow uint64 `json:"max-evictions-per-window"`
+	// Time window of the eviction budget, unit: s.
+	EvictionBudgetWindow uint64 `json:"eviction-budget-window"`
+	// Whether to regard the store with a sustained write stall as a candidate,
+	// no matter what its slow trend is.
+	EvictOnWriteStall bool `json:"evict-on-write-stall"`
+	// The duration of the write stall to regard the store as a candidate, unit: s.
+	WriteStallDuration uint64 `json:"write-stall-duration"`
+	// The number of samples observing the candidate as slow which are required
+	// before evicting it, the first one is taken when capturing the candidate.
+	ConfirmationSamples uint64 `json:"confirmation-samples"`
+	// The minimum interval between two samples of the candidate, unit: s.
+	ConfirmationInterval uint64 `json:"confirmation-interval"`
+	// Whether to spread the evicted leaders of one batch across the target
+	// stores instead of letting them be concentrated on one store.
+	ScatterOnEvict bool `json:"scatter-on-evict"`
+	// The level of logging the routine state transitions, such as waiting and
+	// continuing, can be "normal" or "verbose". The evictions and recoveries
+	// are always logged at info level.
+	LogLevel string `json:"log-level"`
+	// The mode to detect the slow store, can be "cross-sectional" which
+	// compares the store with other stores, or "longitudinal" which compares
+	// the store with its own baseline.
+	DetectionMode string `json:"detection-mode"`
+	// The ratio of `CauseValue` to its baseline to regard the store as
+	// regressed in the longitudinal mode.
+	BaselineRegressionRatio float64 `json:"baseline-regression-ratio"`
+	// Whether the preparing stores take part in the detection. They are still
+	// catching up and may be slower than others naturally.
+	ConsiderPreparingStores bool `json:"consider-preparing-stores"`
+	// The minimum impact score of the candidate to evict it, the score is the
+	// number of leaders it holds weighted by their sizes. 0 means no limit.
+	MinImpactScore float64 `json:"min-impact-score"`
+	// The number of consecutive ticks the evicted store must look fast before
+	// it's recovered, to avoid recovering a flapping store.
+	RecoveryStabilityWindow uint64 `json:"recovery-stability-window"`
+	// The minimum used space ratio of the candidate, the slow stores with more
+	// free space are not captured since evicting leaders helps little. 0 means no limit.
+	MinUsedSpaceRatio float64 `json:"min-used-space-ratio"`
+	// The timeout of the operators evicting leaders, so that the stalled transfers
+	// can be canceled and retried to other stores sooner, unit: s. 0 means the
+	// default timeout of the operators.
+	EvictOperatorTimeout uint64 `json:"evict-operator-timeout"`
+	// The stores whose uptime is less than it are not captured, since they look
+	// slow naturally while warming up after restarting, unit: s. 0 means disabled.
+	WarmupDuration uint64 `json:"warmup-duration"`
+	// The minimum number of leaders in the cluster to evict the slow store, since
+	// evicting leaders in a tiny cluster is disruptive and helps little. 0 means
+	// no limit.
+	MinClusterLeaderCount uint64 `json:"min-cluster-leader-count"`
+	// Whether to skip notifying the cluster that the evicted store is recovered
+	// when it's cleaned up because of being removed, since it's not healthy again.
+	SkipRecoveredOnRemoval bool `json:"skip-recovered-on-removal"`
+	// The confidence of the store to be captured, the confidence rises by 1 in
+	// each tick the store matches the slow trend pattern, and decays by
+	// `ConfidenceDecay` in each tick it doesn't. 0 means disabled.
+	ConfidenceThreshold float64 `json:"confidence-threshold"`
+	// The decay of the confidence in each tick the store doesn't match the slow
+	// trend pattern.
+	ConfidenceDecay float64 `json:"confidence-decay"`
+	// Whether to pick the store with the most severe slow trend when there are
+	// multiple candidates, rather than capturing none of them.
+	PickWorstCandidate bool `json:"pick-worst-candidate"`
+	// The threshold of the tail latency reported by the store to capture it as a
+	// candidate, no matter what its slow trend is, unit: us. 0 means disabled.
+	TailLatencyThreshold uint64 `json:"tail-latency-threshold"`
+	// The daily maintenance window during which the scheduler does nothing, in the
+	// format of "15:04". The window crosses midnight if the start is later than
+	// the end. Empty means no maintenance window.
+	MaintenanceWindowStart string `json:"maintenance-window-start"`
+	// The end of the daily maintenance window, in the format of "15:04".
+	MaintenanceWindowEnd string `json:"maintenance-window-end"`
+	// The IANA time zone of the maintenance window, such as "Asia/Shanghai". Empty
+	// means UTC.
+	MaintenanceWindowTimezone string `json:"maintenance-window-timezone"`
+	// The method to confirm the candidate is slower than others, "pairwise" counts
+	// the stores it is slower than, "median" compares it with the median of others.
+	ComparisonMethod string `json:"comparison-method"`
+	// The key ranges of the critical regions, such as the meta regions, whose
+	// leaders are protected from being evicted casually.
+	CriticalKeyRanges []core.KeyRange `json:"critical-key-ranges"`
+	// The policy of evicting the leaders of the critical regions, "skip" never
+	// evicts them, "last" evicts them after the other leaders are evicted.
+	CriticalRegionPolicy string `json:"critical-region-policy"`
+	// The engine of the stores which can be captured as the candidate, such as
+	// "tikv" or "tiflash". Empty means all engines.
+	EngineFilter string `json:"engine-filter"`
+	// The epsilon used in the comparisons of the slow trends, a larger one filters
+	// the jitters of the noisy stores.
+	ComparisonEpsilon float64 `json:"comparison-epsilon"`
+	// The URL of the webhook to post the events of the eviction lifecycle to, such
+	// as capturing, evicting and recovering the store. Empty means disabled.
+	WebhookURL string `json:"webhook-url"`
+	// The minimum drop of `ResultRate` of the candidate, the stores with a slighter
+	// drop of QPS are not captured. 0 means any drop.
+	MinResultRateDrop float64 `json:"min-result-rate-drop"`
+	// The key ranges whose leaders are evicted from the evicted store, the leaders
+	// out of them are left put. Empty means the whole key space.
+	KeyRanges []core.KeyRange `json:"key-ranges"`
+	// The policy when the eviction decisions fail to be persisted, "retry" keeps
+	// the decisions in memory and persists them again in the following
+	// schedules, "rollback" rolls them back to match the storage.
+	PersistFailurePolicy string `json:"persist-failure-policy"`
+	// Whether to evict the stores slow on disk io, which is detected by the cause
+	// and the result of the slow trend. If disabled, they are only alerted.
+	EnableDiskSlowEvict bool `json:"enable-disk-slow-evict"`
+	// Whether to evict the stores slow on network io, which is detected by the
+	// cause of the slow trend in raft-kv2. If disabled, they are only alerted.
+	EnableNetworkSlowEvict bool `json:"enable-network-slow-evict"`
+	// The label of the failure domain, such as "rack" or "zone". At most one store
+	// in a failure domain can be evicted at a time. Empty means disabled.
+	FailureDomainLabel string `json:"failure-domain-label"`
+	// The number of evictions of a store within `FlapWindow` to regard it as
+	// flapping between evicted and recovered. 0 means disabled.
+	FlapThreshold uint64 `json:"flap-threshold"`
+	// The window to detect the flapping stores, unit: s.
+	FlapWindow uint64 `json:"flap-window"`
+	// The duration to hold a flapping store evicted before it can recover, unit: s.
+	FlapHoldDuration uint64 `json:"flap-hold-duration"`
+	// Whether to lower the leader weight of the evicted store in proportion to
+	// its slowness rather than evicting all its leaders, so that the leaders
+	// are shed gradually by balance-leader.
+	SoftEvict bool `json:"soft-evict"`
+	// The limit of the operators evicting the leaders, which is dedicated to the
+	// scheduler. 0 means sharing the leader schedule limit with other schedulers.
+	EvictOperatorLimit uint64 `json:"evict-operator-limit"`
+	// The batch size of evicting the leaders right after the store is evicted, it
+	// increases by `EvictRampStep` every `EvictRampInterval` up to the full batch.
+	// 0 means evicting the leaders in the full batch at once.
+	EvictRampInitialBatch uint64 `json:"evict-ramp-initial-batch"`
+	// The step to increase the batch size of evicting the leaders.
+	EvictRampStep uint64 `json:"evict-ramp-step"`
+	// The interval to increase the batch size of evicting the leaders, unit: s.
+	EvictRampInterval uint64 `json:"evict-ramp-interval"`
+	// PerStoreRecoveryGap overrides RecoveryDurationGap for the specific stores, e.g.,
+	// the stores with the known flaky hardware, unit: s.
+	PerStoreRecoveryGap map[uint64]uint64 `json:"per-store-recovery-duration"`
+	// EvictionSLA is the expected longest duration of a store being evicted, beyond
+	// which it likely needs the human intervention, 0 means no SLA, unit: s.
+	EvictionSLA uint64 `json:"eviction-sla"`
+	// QuorumDenominator decides which stores count toward the total in the ratio
+	// and quorum math, "all" stores or only the "eligible" ones.
+	QuorumDenominator string `json:"quorum-denominator"`
+	// RegionAwareAffectedCount only counts the slow store and the stores hosting the
+	// replicas of its leader regions as the affected stores, which implies a shared
+	// dependency instead of the coincidental slowness.
+	RegionAwareAffectedCount bool `json:"region-aware-affected-count"`
+	// CandidateCancelCooldown is the duration after a candidate is canceled during
+	// which the same store can not be re-captured as a candidate, 0 means no
+	// cooldown, unit: s.
+	CandidateCancelCooldown uint64 `json:"candidate-cancel-cooldown"`
+	// MinTrendDataAge is the duration a store should have been reporting the slow
+	// trend for before its values are trusted, 0 means trusting them at once,
+	// unit: s.
+	MinTrendDataAge uint64 `json:"min-trend-data-age"`
+	// AutoDisableAfterIdle is the duration without any capture or eviction after
+	// which the scheduler removes itself, e.g., it is enabled temporarily during
+	// an incident, 0 means never, unit: s.
+	AutoDisableAfterIdle uint64 `json:"auto-disable-after-idle"`
+	// The label of the reference group, such as "role". A store is only compared
+	// with the stores of the same label value when checking whether it is slower
+	// or faster than others, empty means comparing with all the stores.
+	ReferenceGroupLabel string `json:"reference-group-label"`
+	// EvictSettlePeriod is the duration after the eviction starts during which the
+	// recovery of the evicted store is not evaluated, letting the eviction take
+	// effect, 0 means evaluating it at once, unit: s.
+	EvictSettlePeriod uint64 `json:"evict-settle-period"`
+	// RecoveryFastScans is the number of consecutive scans observing the evicted
+	// store faster than others to recover it even before the recovery duration
+	// gap elapses, 0 means only the time-based criteria are used.
+	RecoveryFastScans uint64 `json:"recovery-fast-scans"`
+	// RecoveryQuorumRatio is the ratio of the other stores the evicted store must
+	// be faster than to recover it, 0 means the majority of the stores.
+	RecoveryQuorumRatio float64 `json:"recovery-quorum-ratio"`
+	// Whether to keep the evicted store evicted while it is still slower than
+	// others by the capture standard, so that it is not recovered and then
+	// re-captured at once.
+	SymmetricRecovery bool `json:"symmetric-recovery"`
+	// OfflineEvictedStorePolicy is the policy of handling the evicted store which
+	// goes offline, i.e., it is being decommissioned, it is one of "keep" and
+	// "stop".
+	OfflineEvictedStorePolicy string `json:"offline-evicted-store-policy"`
+	// Whether to evaluate the affected store ratio against the eligible stores
+	// only, e.g., the serving ones, rather than all the stores. It only affects
+	// the affected store ratio, see `QuorumDenominator` for the others.
+	AffectedRatioOverEligible bool `json:"affected-ratio-over-eligible"`
+	// The fractions of the original leader weight which the recovered store is
+	// re-admitted with stage by stage, e.g., [0.25, 0.5], so that it takes a
+	// part of its normal leader load before being fully restored. Empty means
+	// the store is restored at once.
+	ReadmissionStages []float64 `json:"readmission-stages"`
+	// The duration of each stage of the re-admission, unit: s.
+	ReadmissionStageDuration uint64 `json:"readmission-stage-duration"`
+	// Whether the evicted store is required to report the "become fast" event, i.e.,
+	// its own slow trend is improving, to be recovered besides being faster than
+	// others. It may hold the store evicted after it restarts, since TiKV does not
+	// report the event until its detection windows are filled.
+	RequireBecomeFastEvent bool `json:"require-become-fast-event"`
+	// The ratio of the stores which should be affected by the slow store to capture
+	// it, 0 means `slow-store-evicting-affected-store-ratio-threshold` of the
+	// scheduler config is used.
+	AffectedStoreRatioThreshold float64 `json:"affected-store-ratio-threshold"`
+	// Profile is the preset of the detection sensitivity, which expands to the
+	// coherent thresholds once it is set, and the items set along with it take
+	// precedence, see `slowTrendProfiles`. Empty means the thresholds are tuned
+	// individually.
+	Profile string `json:"profile"`
+	// The key ranges of the latency-critical data, e.g., some tables, whose leaders
+	// are evicted before the others so that the most affected traffic is relieved
+	// soonest. They only take effect within `KeyRanges`.
+	PriorityKeyRanges []core.KeyRange `json:"priority-key-ranges"`
+	// The minimum number of the stores to evict the slow store, the ongoing eviction
+	// is halted if the stores are removed to fewer than it. It is at least 3.
+	MinStoresForEvicting uint64 `json:"min-stores-for-evicting"`
+	// Timestamps of the recent evictions, used to calculate the eviction budget.
+	RecentEvictions []time.Time `json:"recent-evictions"`
+	// Candidate for eviction in current tick.
+	EvictCandidate slowCandidate `json:"evict-candidate"`
+	// Last chosen candidate for eviction.
+	LastEvictCandidate slowCandidate `json:"last-evict-candidate"`
 	// Only evict one store for now
 	EvictedStores []uint64 `json:"evict-by-trend-stores"`
+	// The reasons annotated by hand to the stores in `EvictedStores`, e.g., why
+	// the store is evicted, it's only for the handoffs between the operators.
+	EvictedReasons map[uint64]string `json:"evicted-reasons"`
+	// Timestamp of evicting the store in `EvictedStores`.
+	EvictedTS time.Time `json:"evicted-ts"`
+	// The scheduler is paused until this deadline.
+	PausedUntil time.Time `json:"paused-until"`
+	// The confidence of each store being slow. It's updated in memory in each
+	// tick and persisted along with other states.
+	Confidences map[uint64]float64 `json:"confidences"`
+	// The number of times each store has been evicted, a store evicted again
+	// and again is likely to have problematic hardware. The removed stores are
+	// pruned in the next eviction.
+	EvictionCounts map[uint64]uint64 `json:"eviction-counts"`
+	// Timestamp of the latest capture or eviction, used to check whether the
+	// scheduler is idle.
+	LastActiveTS time.Time `json:"last-active-ts"`
+	// The leader weight of the soft evicted store changed by the scheduler, the
+	// original one is restored once the store is not evicted anymore.
+	SoftEvictedWeight leaderWeightChange `json:"soft-evicted-weight"`
+	// The store being re-admitted after recovery, its leader weight is ramped
+	// up to the original one by `ReadmissionStages`.
+	Readmission storeReadmission `json:"readmission"`
 }
 
 func initEvictSlowTrendSchedulerConfig(storage endpoint.ConfigStorage) *evictSlowTrendSchedulerConfig {
 	return &evictSlowTrendSchedulerConfig{
-		storage:             storage,
-		evictCandidate:      slowCandidate{},
-		lastEvictCandidate:  slowCandidate{},
-		RecoveryDurationGap: defaultRecoveryDurationGap,
-		EvictedStores:       make([]uint64, 0),
+		storage:                   storage,
+		now:                       time.Now,
+		EvictCandidate:            slowCandidate{},
+		LastEvictCandidate:        slowCandidate{},
+		RecoveryDurationGap:       defaultRecoveryDurationGap,
+		EvictionBudgetWindow:      defaultEvictionBudgetWindow,
+		WriteStallDuration:        defaultWriteStallDuration,
+		ConfirmationSamples:       defaultConfirmationSamples,
+		ConfirmationInterval:      defaultConfirmationInterval,
+		BaselineRegressionRatio:   defaultBaselineRegressionRatio,
+		LogLevel:                  slowTrendLogLevelNormal,
+		ConsiderPreparingStores:   true,
+		DetectionMode:             slowTrendDetectionModeCrossSectional,
+		ConfidenceDecay:           defaultConfidenceDecay,
+		ComparisonMethod:          slowTrendComparisonPairwise,
+		CriticalRegionPolicy:      criticalRegionPolicySkip,
+		ComparisonEpsilon:         alterEpsilon,
+		PersistFailurePolicy:      persistFailurePolicyRetry,
+		EnableDiskSlowEvict:       true,
+		EnableNetworkSlowEvict:    true,
+		FlapWindow:                defaultFlapWindow,
+		FlapHoldDuration:          defaultFlapHoldDuration,
+		EvictRampStep:             defaultEvictRampStep,
+		EvictRampInterval:         defaultEvictRampInterval,
+		PerStoreRecoveryGap:       make(map[uint64]uint64),
+		EvictionSLA:               defaultEvictionSLA,
+		QuorumDenominator:         quorumDenominatorAll,
+		OfflineEvictedStorePolicy: offlineEvictedStorePolicyKeep,
+		MinStoresForEvicting:      defaultMinStoresForEvicting,
+		EvictedStores:             make([]uint64, 0),
+		EvictedReasons:            make(map[uint64]string),
+		Confidences:               make(map[uint64]float64),
+		EvictionCounts:            make(map[uint64]uint64),
+		writeStallSince:           make(map[uint64]time.Time),
+		causeValueBaselines:       make(map[uint64]float64),
+		evictionHistory:           make(map[uint64][]time.Time),
+		candidateCanceledAt:       make(map[uint64]time.Time),
+		trendFirstSeen:            make(map[uint64]trendFirstSeen),
 	}
 }
 
 func (conf *evictSlowTrendSchedulerConfig) Clone() *evictSlowTrendSchedulerConfig {
 	conf.RLock()
 	defer conf.RUnlock()
+	recentEvictions := make([]time.Time, len(conf.RecentEvictions))
+	copy(recentEvictions, conf.RecentEvictions)
+	evictionCounts := make(map[uint64]uint64, len(conf.EvictionCounts))
+	for storeID, count := range conf.EvictionCounts {
+		evictionCounts[storeID] = count
+	}
+	perStoreRecoveryGap := make(map[uint64]uint64, len(conf.PerStoreRecoveryGap))
+	for storeID, gap := range conf.PerStoreRecoveryGap {
+		perStoreRecoveryGap[storeID] = gap
+	}
 	return &evictSlowTrendSchedulerConfig{
-		RecoveryDurationGap: conf.RecoveryDurationGap,
+		now:                          conf.now,
+		RecoveryDurationGap:          conf.RecoveryDurationGap,
+		RecoveryGapScalingFactor:     conf.RecoveryGapScalingFactor,
+		MaxRecoveryDurationGap:       conf.MaxRecoveryDurationGap,
+		UseSlowScore:                 conf.UseSlowScore,
+		ResultFieldsOptional:         conf.ResultFieldsOptional,
+		ClusterSlowCauseValueCeiling: conf.ClusterSlowCauseValueCeiling,
+		RebalanceOnRecover:           conf.RebalanceOnRecover,
+		MaxEvictionsPerWindow:        conf.MaxEvictionsPerWindow,
+		EvictionBudgetWindow:         conf.EvictionBudgetWindow,
+		EvictOnWriteStall:            conf.EvictOnWriteStall,
+		WriteStallDuration:           conf.WriteStallDuration,
+		ConfirmationSamples:          conf.ConfirmationSamples,
+		ConfirmationInterval:         conf.ConfirmationInterval,
+		ScatterOnEvict:               conf.ScatterOnEvict,
+		LogLevel:                     conf.LogLevel,
+		DetectionMode:                conf.DetectionMode,
+		BaselineRegressionRatio:      conf.BaselineRegressionRatio,
+		ConsiderPreparingStores:      conf.ConsiderPreparingStores,
+		MinImpactScore:               conf.MinImpactScore,
+		RecoveryStabilityWindow:      conf.RecoveryStabilityWindow,
+		MinUsedSpaceRatio:            conf.MinUsedSpaceRatio,
+		EvictOperatorTimeout:         conf.EvictOperatorTimeout,
+		WarmupDuration:               conf.WarmupDuration,
+		MinClusterLeaderCount:        conf.MinClusterLeaderCount,
+		SkipRecoveredOnRemoval:       conf.SkipRecoveredOnRemoval,
+		ConfidenceThreshold:          conf.ConfidenceThreshold,
+		ConfidenceDecay:              conf.ConfidenceDecay,
+		PickWorstCandidate:           conf.PickWorstCandidate,
+		TailLatencyThreshold:         conf.TailLatencyThreshold,
+		MaintenanceWindowStart:       conf.MaintenanceWindowStart,
+		MaintenanceWindowEnd:         conf.MaintenanceWindowEnd,
+		MaintenanceWindowTimezone:    conf.MaintenanceWindowTimezone,
+		ComparisonMethod:             conf.ComparisonMethod,
+		CriticalKeyRanges:            conf.CriticalKeyRanges,
+		CriticalRegionPolicy:         conf.CriticalRegionPolicy,
+		EngineFilter:                 conf.EngineFilter,
+		ComparisonEpsilon:            conf.ComparisonEpsilon,
+		WebhookURL:                   conf.WebhookURL,
+		MinResultRateDrop:            conf.MinResultRateDrop,
+		KeyRanges:                    conf.KeyRanges,
+		PersistFailurePolicy:         conf.PersistFailurePolicy,
+		EnableDiskSlowEvict:          conf.EnableDiskSlowEvict,
+		EnableNetworkSlowEvict:       conf.EnableNetworkSlowEvict,
+		FailureDomainLabel:           conf.FailureDomainLabel,
+		FlapThreshold:                conf.FlapThreshold,
+		FlapWindow:                   conf.FlapWindow,
+		FlapHoldDuration:             conf.FlapHoldDuration,
+		SoftEvict:                    conf.SoftEvict,
+		EvictOperatorLimit:           conf.EvictOperatorLimit,
+		EvictRampInitialBatch:        conf.EvictRampInitialBatch,
+		EvictRampStep:                conf.EvictRampStep,
+		EvictRampInterval:            conf.EvictRampInterval,
+		PerStoreRecoveryGap:          perStoreRecoveryGap,
+		EvictionSLA:                  conf.EvictionSLA,
+		QuorumDenominator:            conf.QuorumDenominator,
+		RegionAwareAffectedCount:     conf.RegionAwareAffectedCount,
+		CandidateCancelCooldown:      conf.CandidateCancelCooldown,
+		MinTrendDataAge:              conf.MinTrendDataAge,
+		AutoDisableAfterIdle:         conf.AutoDisableAfterIdle,
+		ReferenceGroupLabel:          conf.ReferenceGroupLabel,
+		EvictSettlePeriod:            conf.EvictSettlePeriod,
+		RecoveryFastScans:            conf.RecoveryFastScans,
+		RecoveryQuorumRatio:          conf.RecoveryQuorumRatio,
+		SymmetricRecovery:            conf.SymmetricRecovery,
+		OfflineEvictedStorePolicy:    conf.OfflineEvictedStorePolicy,
+		AffectedRatioOverEligible:    conf.AffectedRatioOverEligible,
+		ReadmissionStages:            conf.ReadmissionStages,
+		ReadmissionStageDuration:     conf.ReadmissionStageDuration,
+		RequireBecomeFastEvent:       conf.RequireBecomeFastEvent,
+		AffectedStoreRatioThreshold:  conf.AffectedStoreRatioThreshold,
+		Profile:                      conf.Profile,
+		PriorityKeyRanges:            conf.PriorityKeyRanges,
+		MinStoresForEvicting:         conf.MinStoresForEvicting,
+		RecentEvictions:              recentEvictions,
+		EvictionCounts:               evictionCounts,
+	}
+}
+
+// EffectiveConfig returns the config with all the items resolved to the values
+// in effect. The items left empty in the persisted config fall back to their
+// defaults at runtime, they are filled here so that the operators can see
+// exactly what's in force.
+func (conf *evictSlowTrendSchedulerConfig) EffectiveConfig() *evictSlowTrendSchedulerConfig {
+	cfg := conf.Clone()
+	conf.RLock()
+	cfg.EvictCandidate, cfg.LastEvictCandidate = conf.EvictCandidate, conf.LastEvictCandidate
+	cfg.EvictedStores = append(make([]uint64, 0, len(conf.EvictedStores)), conf.EvictedStores...)
+	cfg.EvictedReasons = make(map[uint64]string, len(conf.EvictedReasons))
+	for storeID, reason := range conf.EvictedReasons {
+		cfg.EvictedReasons[storeID] = reason
+	}
+	cfg.EvictedTS, cfg.PausedUntil, cfg.LastActiveTS = conf.EvictedTS, conf.PausedUntil, conf.LastActiveTS
+	cfg.SoftEvictedWeight, cfg.Readmission = conf.SoftEvictedWeight, conf.Readmission
+	cfg.Confidences = make(map[uint64]float64, len(conf.Confidences))
+	for storeID, confidence := range conf.Confidences {
+		cfg.Confidences[storeID] = confidence
+	}
+	conf.RUnlock()
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = slowTrendLogLevelNormal
+	}
+	if cfg.DetectionMode == "" {
+		cfg.DetectionMode = slowTrendDetectionModeCrossSectional
+	}
+	if cfg.ComparisonMethod == "" {
+		cfg.ComparisonMethod = slowTrendComparisonPairwise
+	}
+	if cfg.CriticalRegionPolicy == "" {
+		cfg.CriticalRegionPolicy = criticalRegionPolicySkip
+	}
+	if cfg.QuorumDenominator == "" {
+		cfg.QuorumDenominator = quorumDenominatorAll
+	}
+	if cfg.PersistFailurePolicy == "" {
+		cfg.PersistFailurePolicy = persistFailurePolicyRetry
+	}
+	if cfg.OfflineEvictedStorePolicy == "" {
+		cfg.OfflineEvictedStorePolicy = offlineEvictedStorePolicyKeep
+	}
+	if cfg.MaintenanceWindowTimezone == "" {
+		cfg.MaintenanceWindowTimezone = time.UTC.String()
+	}
+	if cfg.CriticalKeyRanges == nil {
+		cfg.CriticalKeyRanges = []core.KeyRange{}
+	}
+	if cfg.MinStoresForEvicting == 0 {
+		cfg.MinStoresForEvicting = defaultMinStoresForEvicting
+	}
+	if cfg.PriorityKeyRanges == nil {
+		cfg.PriorityKeyRanges = []core.KeyRange{}
+	}
+	if cfg.ReadmissionStages == nil {
+		cfg.ReadmissionStages = []float64{}
+	}
+	if len(cfg.KeyRanges) == 0 {
+		cfg.KeyRanges = []core.KeyRange{core.NewKeyRange("", "")}
+	}
+	return cfg
+}
+
+// state returns a snapshot of the candidate and eviction states.
+func (conf *evictSlowTrendSchedulerConfig) state() slowTrendState {
+	conf.RLock()
+	defer conf.RUnlock()
+	evictedStores := make([]uint64, len(conf.EvictedStores))
+	copy(evictedStores, conf.EvictedStores)
+	return slowTrendState{
+		EvictCandidate:     conf.EvictCandidate,
+		LastEvictCandidate: conf.LastEvictCandidate,
+		EvictedStores:      evictedStores,
+	}
+}
+
+// stateName returns the name of the current lifecycle state.
+func (conf *evictSlowTrendSchedulerConfig) stateName() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	if len(conf.EvictedStores) > 0 {
+		return slowTrendStateEvicting
+	}
+	if conf.EvictCandidate.StoreID != 0 {
+		return slowTrendStateCandidatePending
+	}
+	return slowTrendStateIdle
+}
+
+func (conf *evictSlowTrendSchedulerConfig) update(data []byte) (int, any) {
+	conf.Lock()
+	defer conf.Unlock()
+
+	oldConfig, _ := json.Marshal(conf)
+	// The states are maintained by the scheduler itself and can not be
+	// modified by the config API.
+	evictedStores, evictedTS, recentEvictions := conf.EvictedStores, conf.EvictedTS, conf.RecentEvictions
+	evictCandidate, lastEvictCandidate, pausedUntil := conf.EvictCandidate, conf.LastEvictCandidate, conf.PausedUntil
+	lastActiveTS, softEvictedWeight, readmission := conf.LastActiveTS, conf.SoftEvictedWeight, conf.Readmission
+	confidences, evictionCounts, evictedReasons := conf.Confidences, conf.EvictionCounts, conf.EvictedReasons
+	// Unmarshal the maps into new ones rather than merging into the states.
+	conf.Confidences, conf.EvictionCounts, conf.EvictedReasons = nil, nil, nil
+	if err := json.Unmarshal(data, conf); err != nil {
+		json.Unmarshal(oldConfig, conf)
+		return http.StatusInternalServerError, err.Error()
+	}
+	conf.EvictedStores, conf.EvictedTS, conf.RecentEvictions = evictedStores, evictedTS, recentEvictions
+	conf.EvictCandidate, conf.LastEvictCandidate, conf.PausedUntil = evictCandidate, lastEvictCandidate, pausedUntil
+	conf.LastActiveTS, conf.SoftEvictedWeight, conf.Readmission = lastActiveTS, softEvictedWeight, readmission
+	conf.Confidences, conf.EvictionCounts, conf.EvictedReasons = confidences, evictionCounts, evictedReasons
+	if err := conf.expandProfileLocked(data); err != nil {
+		json.Unmarshal(oldConfig, conf)
+		return http.StatusInternalServerError, err.Error()
+	}
+	if err := conf.validateLocked(); err != nil {
+		json.Unmarshal(oldConfig, conf)
+		return http.StatusBadRequest, err.Error()
+	}
+	newConfig, _ := json.Marshal(conf)
+	if !bytes.Equal(oldConfig, newConfig) {
+		if err := conf.persistLocked(); err != nil {
+			json.Unmarshal(oldConfig, conf)
+			return http.StatusInternalServerError, err.Error()
+		}
+		log.Info("evict-slow-trend-scheduler config is updated", zap.ByteString("old", oldConfig), zap.ByteString("new", newConfig))
+		return http.StatusOK, "Config updated."
+	}
+	m := make(map[string]any)
+	if err := json.Unmarshal(data, &m); err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if reflectutil.FindSameFieldByJSON(conf, m) {
+		return http.StatusOK, "Config is the same with origin, so do nothing."
+	}
+	return http.StatusBadRequest, "Config item is not found."
+}
+
+// expandProfileLocked sets the thresholds by the profile if it's set in the
+// given config, the items set explicitly in the config are kept.
+func (conf *evictSlowTrendSchedulerConfig) expandProfileLocked(data []byte) error {
+	explicit := make(map[string]any)
+	if err := json.Unmarshal(data, &explicit); err != nil {
+		return err
+	}
+	if _, ok := explicit["profile"]; !ok {
+		return nil
+	}
+	profile, ok := slowTrendProfiles[conf.Profile]
+	if !ok {
+		// It's rejected by the validation if it's not empty.
+		return nil
+	}
+	if _, ok := explicit["affected-store-ratio-threshold"]; !ok {
+		conf.AffectedStoreRatioThreshold = profile.AffectedStoreRatioThreshold
+	}
+	if _, ok := explicit["recovery-quorum-ratio"]; !ok {
+		conf.RecoveryQuorumRatio = profile.RecoveryQuorumRatio
+	}
+	if _, ok := explicit["recovery-duration"]; !ok {
+		conf.RecoveryDurationGap = profile.RecoveryDurationGap
+	}
+	if _, ok := explicit["comparison-epsilon"]; !ok {
+		conf.ComparisonEpsilon = profile.ComparisonEpsilon
+	}
+	return nil
+}
+
+func (conf *evictSlowTrendSchedulerConfig) validateLocked() error {
+	switch conf.ComparisonMethod {
+	case "", slowTrendComparisonPairwise, slowTrendComparisonMedian:
+	default:
+		return errors.Errorf("invalid comparison method %q", conf.ComparisonMethod)
+	}
+	switch conf.QuorumDenominator {
+	case "", quorumDenominatorAll, quorumDenominatorEligible:
+	default:
+		return errors.Errorf("invalid quorum denominator %q", conf.QuorumDenominator)
 	}
+	switch conf.CriticalRegionPolicy {
+	case "", criticalRegionPolicySkip, criticalRegionPolicyLast:
+	default:
+		return errors.Errorf("invalid critical region policy %q", conf.CriticalRegionPolicy)
+	}
+	switch conf.OfflineEvictedStorePolicy {
+	case "", offlineEvictedStorePolicyKeep, offlineEvictedStorePolicyStop:
+	default:
+		return errors.Errorf("invalid offline evicted store policy %q", conf.OfflineEvictedStorePolicy)
+	}
+	switch conf.PersistFailurePolicy {
+	case "", persistFailurePolicyRetry, persistFailurePolicyRollback:
+	default:
+		return errors.Errorf("invalid persist failure policy %q", conf.PersistFailurePolicy)
+	}
+	if _, ok := slowTrendProfiles[conf.Profile]; conf.Profile != "" && !ok {
+		return errors.Errorf("invalid profile %q", conf.Profile)
+	}
+	switch conf.EngineFilter {
+	case "", core.EngineTiKV, core.EngineTiFlash:
+	default:
+		return errors.Errorf("invalid engine filter %q", conf.EngineFilter)
+	}
+	if conf.ComparisonEpsilon <= 0 {
+		return errors.Errorf("comparison epsilon %v is not positive", conf.ComparisonEpsilon)
+	}
+	if conf.MinStoresForEvicting != 0 && conf.MinStoresForEvicting < defaultMinStoresForEvicting {
+		return errors.Errorf("min stores for evicting %v is less than %v", conf.MinStoresForEvicting, defaultMinStoresForEvicting)
+	}
+	if conf.AffectedStoreRatioThreshold < 0 || conf.AffectedStoreRatioThreshold > 1 {
+		return errors.Errorf("affected store ratio threshold %v is out of range [0, 1]", conf.AffectedStoreRatioThreshold)
+	}
+	if conf.RecoveryQuorumRatio < 0 || conf.RecoveryQuorumRatio > 1 {
+		return errors.Errorf("recovery quorum ratio %v is out of range [0, 1]", conf.RecoveryQuorumRatio)
+	}
+	if conf.MinResultRateDrop < 0 {
+		return errors.Errorf("min result rate drop %v is negative", conf.MinResultRateDrop)
+	}
+	for i, stage := range conf.ReadmissionStages {
+		if stage <= 0 || stage > 1 {
+			return errors.Errorf("readmission stage %v is out of range (0, 1]", stage)
+		}
+		if i > 0 && stage < conf.ReadmissionStages[i-1] {
+			return errors.Errorf("readmission stages %v are not ascending", conf.ReadmissionStages)
+		}
+	}
+	if conf.MaintenanceWindowStart != "" || conf.MaintenanceWindowEnd != "" {
+		if _, _, _, err := parseMaintenanceWindow(conf.MaintenanceWindowStart, conf.MaintenanceWindowEnd, conf.MaintenanceWindowTimezone); err != nil {
+			return err
+		}
+	}
+	return conf.checkContradictionLocked()
+}
+
+// checkContradictionLocked checks whether some items of the config contradict
+// others, which makes them take no effect silently.
+func (conf *evictSlowTrendSchedulerConfig) checkContradictionLocked() error {
+	if conf.MaxRecoveryDurationGap > 0 && conf.RecoveryGapScalingFactor <= 0 {
+		return errors.New("max-recovery-duration takes no effect unless recovery-gap-scaling-factor is set")
+	}
+	if len(conf.ReadmissionStages) > 0 && conf.ReadmissionStageDuration == 0 {
+		return errors.New("readmission-stages takes no effect unless readmission-stage-duration is set")
+	}
+	if conf.MaxEvictionsPerWindow > 0 && conf.EvictionBudgetWindow == 0 {
+		return errors.New("max-evictions-per-window takes no effect unless eviction-budget-window is set")
+	}
+	if conf.ComparisonMethod == slowTrendComparisonMedian && conf.DetectionMode == slowTrendDetectionModeLongitudinal {
+		return errors.New("comparison-method takes no effect in the longitudinal detection mode")
+	}
+	if conf.FlapThreshold > 0 && conf.FlapWindow == 0 {
+		return errors.New("flap-threshold takes no effect unless flap-window is set")
+	}
+	if conf.MaintenanceWindowStart != "" && conf.MaintenanceWindowStart == conf.MaintenanceWindowEnd {
+		return errors.New("maintenance window never takes effect since its start is the same as its end")
+	}
+	return nil
+}
+
+// contradiction returns the error if the config is contradictory, no new store
+// is captured until it's fixed, while the existing evictions still recover.
+func (conf *evictSlowTrendSchedulerConfig) contradiction() error {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.checkContradictionLocked()
 }
 
 func (conf *evictSlowTrendSchedulerConfig) persistLocked() error {
@@ -94,133 +1076,1286 @@ func (conf *evictSlowTrendSchedulerConfig) persistLocked() error {
 	if err != nil {
 		return err
 	}
-	return conf.storage.SaveSchedulerConfig(name, data)
+	if err := conf.storage.SaveSchedulerConfig(name, data); err != nil {
+		return err
+	}
+	conf.persistPending = false
+	return nil
 }
 
-func (conf *evictSlowTrendSchedulerConfig) getStores() []uint64 {
-	conf.RLock()
-	defer conf.RUnlock()
-	return conf.EvictedStores
+// persistDecisionLocked persists the eviction decision just made in memory. If
+// it fails, the decision is either rolled back by `rollback` or kept to be
+// persisted again later, according to `PersistFailurePolicy`.
+func (conf *evictSlowTrendSchedulerConfig) persistDecisionLocked(rollback func()) error {
+	err := conf.persistLocked()
+	if err == nil {
+		return nil
+	}
+	if conf.PersistFailurePolicy == persistFailurePolicyRollback {
+		rollback()
+	} else {
+		conf.persistPending = true
+	}
+	return err
 }
 
-func (conf *evictSlowTrendSchedulerConfig) getKeyRangesByID(id uint64) []core.KeyRange {
-	if conf.evictedStore() != id {
+// retryPersist persists the in-memory state again if it failed to be persisted.
+func (conf *evictSlowTrendSchedulerConfig) retryPersist() error {
+	conf.Lock()
+	defer conf.Unlock()
+	if !conf.persistPending {
 		return nil
 	}
-	return []core.KeyRange{core.NewKeyRange("", "")}
+	return conf.persistLocked()
 }
 
-func (conf *evictSlowTrendSchedulerConfig) hasEvictedStores() bool {
+func (conf *evictSlowTrendSchedulerConfig) getStores() []uint64 {
 	conf.RLock()
 	defer conf.RUnlock()
-	return len(conf.EvictedStores) > 0
+	return conf.EvictedStores
 }
 
-func (conf *evictSlowTrendSchedulerConfig) evictedStore() uint64 {
-	if !conf.hasEvictedStores() {
-		return 0
-	}
-	conf.RLock()
-	defer conf.RUnlock()
-	// If a candidate passes all checks and proved to be slow, it will be
-	// recorded in `conf.EvictStores`, and `conf.lastEvictCandidate` will record
-	// the captured timestamp of this store.
-	return conf.EvictedStores[0]
+// SlowTrendStateDrift is the discrepancy between the evicted stores persisted
+// by the evict-slow-trend scheduler and the stores marked as evicted by slow
+// trend in the cluster.
+type SlowTrendStateDrift struct {
+	// PersistedOnly is the stores persisted as evicted but not marked in the cluster.
+	PersistedOnly []uint64 `json:"persisted-only"`
+	// AppliedOnly is the stores marked in the cluster but not persisted as evicted.
+	AppliedOnly []uint64 `json:"applied-only"`
+	Repaired    bool     `json:"repaired"`
 }
 
-func (conf *evictSlowTrendSchedulerConfig) candidate() uint64 {
-	conf.RLock()
-	defer conf.RUnlock()
-	return conf.evictCandidate.storeID
+// hasDrift returns true if the persisted and the applied evicted stores differ.
+func (d *SlowTrendStateDrift) hasDrift() bool {
+	return len(d.PersistedOnly) > 0 || len(d.AppliedOnly) > 0
 }
 
-func (conf *evictSlowTrendSchedulerConfig) captureTS() time.Time {
+// verifyState cross-checks the persisted evicted stores against the stores
+// marked as evicted by slow trend in the cluster, and repairs the cluster to
+// match the persisted state if repair is true. The persisted state is the
+// source of truth, as it's what the scheduler recovers from after restarting.
+func (conf *evictSlowTrendSchedulerConfig) verifyState(cluster sche.BasicCluster, repair bool) *SlowTrendStateDrift {
+	persisted := make(map[uint64]struct{})
+	for _, storeID := range conf.getStores() {
+		persisted[storeID] = struct{}{}
+	}
+	drift := &SlowTrendStateDrift{PersistedOnly: []uint64{}, AppliedOnly: []uint64{}}
+	for storeID := range persisted {
+		// The soft evicted store is not marked in the cluster.
+		if conf.isSoftEvicted(storeID) {
+			continue
+		}
+		if store := cluster.GetStore(storeID); store != nil && !store.IsEvictedAsSlowTrend() {
+			drift.PersistedOnly = append(drift.PersistedOnly, storeID)
+		}
+	}
+	for _, store := range cluster.GetStores() {
+		if _, ok := persisted[store.GetID()]; !ok && store.IsEvictedAsSlowTrend() {
+			drift.AppliedOnly = append(drift.AppliedOnly, store.GetID())
+		}
+	}
+	sort.Slice(drift.PersistedOnly, func(i, j int) bool { return drift.PersistedOnly[i] < drift.PersistedOnly[j] })
+	sort.Slice(drift.AppliedOnly, func(i, j int) bool { return drift.AppliedOnly[i] < drift.AppliedOnly[j] })
+	if !drift.hasDrift() {
+		return drift
+	}
+	conf.getLogger().Warn("evicted stores of slow trend drift from the cluster",
+		zap.Uint64s("persisted-only", drift.PersistedOnly),
+		zap.Uint64s("applied-only", drift.AppliedOnly),
+		zap.Bool("repair", repair))
+	storeSlowTrendActionStatusGauge.WithLabelValues("verify", "drift").Inc()
+	if !repair {
+		return drift
+	}
+	for _, storeID := range drift.PersistedOnly {
+		cluster.SlowTrendEvicted(storeID)
+	}
+	for _, storeID := range drift.AppliedOnly {
+		cluster.SlowTrendRecovered(storeID)
+	}
+	drift.Repaired = true
+	storeSlowTrendActionStatusGauge.WithLabelValues("verify", "repaired").Inc()
+	return drift
+}
+
+// excludedTargets returns the stores which the evicted leaders should not be
+// transferred to, i.e., the candidate, the evicted stores and the other stores
+// slow by trend. The stores evicted by evict-slow-store are rejected by the
+// store state filter already.
+func (conf *evictSlowTrendSchedulerConfig) excludedTargets(cluster sche.SchedulerCluster) map[uint64]struct{} {
 	conf.RLock()
-	defer conf.RUnlock()
-	return conf.evictCandidate.captureTS
+	excluded := make(map[uint64]struct{})
+	if conf.EvictCandidate.StoreID != 0 {
+		excluded[conf.EvictCandidate.StoreID] = struct{}{}
+	}
+	for _, storeID := range conf.EvictedStores {
+		excluded[storeID] = struct{}{}
+	}
+	resultFieldsOptional, epsilon := conf.ResultFieldsOptional, conf.ComparisonEpsilon
+	conf.RUnlock()
+	for _, store := range cluster.GetStores() {
+		if matchSlowTrendPattern(store, resultFieldsOptional, epsilon) {
+			excluded[store.GetID()] = struct{}{}
+		}
+	}
+	return excluded
 }
 
-func (conf *evictSlowTrendSchedulerConfig) candidateCapturedSecs() uint64 {
+func (conf *evictSlowTrendSchedulerConfig) getEvictLeaderOptions(cluster sche.SchedulerCluster) evictLeaderOptions {
 	conf.RLock()
-	defer conf.RUnlock()
-	return DurationSinceAsSecs(conf.evictCandidate.captureTS)
+	opts := evictLeaderOptions{
+		scatter:                 conf.ScatterOnEvict,
+		operatorTimeout:         time.Duration(conf.EvictOperatorTimeout) * time.Second,
+		evictCriticalRegionLast: conf.CriticalRegionPolicy == criticalRegionPolicyLast,
+		priorityKeyRanges:       conf.PriorityKeyRanges,
+	}
+	if len(conf.CriticalKeyRanges) > 0 {
+		opts.criticalRegionFilter = &criticalRegionFilter{ranges: conf.CriticalKeyRanges}
+	}
+	if len(conf.inflightRegions) > 0 {
+		opts.skippedRegionFilter = &inflightRegionFilter{conf: conf}
+	}
+	conf.RUnlock()
+	opts.excludedTargets = conf.excludedTargets(cluster)
+	return opts
 }
 
-func (conf *evictSlowTrendSchedulerConfig) lastCapturedCandidate() *slowCandidate {
+// minStoresForEvicting returns the minimum number of the stores to evict the
+// slow store.
+func (conf *evictSlowTrendSchedulerConfig) minStoresForEvicting() int {
 	conf.RLock()
 	defer conf.RUnlock()
-	return &conf.lastEvictCandidate
+	if conf.MinStoresForEvicting == 0 {
+		return defaultMinStoresForEvicting
+	}
+	return int(conf.MinStoresForEvicting)
 }
 
-func (conf *evictSlowTrendSchedulerConfig) lastCandidateCapturedSecs() uint64 {
-	return DurationSinceAsSecs(conf.lastEvictCandidate.captureTS)
+// countStoresForEvicting counts the stores toward `MinStoresForEvicting`, the
+// removed stores are not counted.
+func countStoresForEvicting(stores []*core.StoreInfo) int {
+	count := 0
+	for _, store := range stores {
+		if !store.IsRemoved() {
+			count++
+		}
+	}
+	return count
 }
 
-// readyForRecovery checks whether the last cpatured candidate is ready for recovery.
-func (conf *evictSlowTrendSchedulerConfig) readyForRecovery() bool {
-	conf.RLock()
-	defer conf.RUnlock()
-	recoveryDurationGap := conf.RecoveryDurationGap
-	failpoint.Inject("transientRecoveryGap", func() {
-		recoveryDurationGap = 0
-	})
-	return conf.lastCandidateCapturedSecs() >= recoveryDurationGap
+// criticalRegionFilter rejects the regions overlapping with the critical key
+// ranges.
+type criticalRegionFilter struct {
+	ranges []core.KeyRange
 }
 
-func (conf *evictSlowTrendSchedulerConfig) captureCandidate(id uint64) {
-	conf.Lock()
-	defer conf.Unlock()
-	conf.evictCandidate = slowCandidate{
-		storeID:   id,
-		captureTS: time.Now(),
-		recoverTS: time.Now(),
-	}
-	if conf.lastEvictCandidate == (slowCandidate{}) {
-		conf.lastEvictCandidate = conf.evictCandidate
+// Select implements filter.RegionFilter.
+func (f *criticalRegionFilter) Select(region *core.RegionInfo) *plan.Status {
+	for _, r := range f.ranges {
+		if (len(r.EndKey) == 0 || bytes.Compare(region.GetStartKey(), r.EndKey) < 0) &&
+			(len(region.GetEndKey()) == 0 || bytes.Compare(r.StartKey, region.GetEndKey()) < 0) {
+			storeSlowTrendActionStatusGauge.WithLabelValues("evict", "skip_critical_region").Inc()
+			return criticalRegionStatus
+		}
 	}
+	return plan.NewStatus(plan.StatusOK)
 }
 
-func (conf *evictSlowTrendSchedulerConfig) popCandidate(updLast bool) uint64 {
+// setInflightRegions replaces the regions with the in-flight operators.
+func (conf *evictSlowTrendSchedulerConfig) setInflightRegions(regions map[uint64]struct{}, hasOperator func(regionID uint64) bool) {
 	conf.Lock()
 	defer conf.Unlock()
-	id := conf.evictCandidate.storeID
-	if updLast {
-		conf.lastEvictCandidate = conf.evictCandidate
-	}
-	conf.evictCandidate = slowCandidate{}
-	return id
+	conf.inflightRegions = regions
+	conf.hasOperator = hasOperator
 }
 
-func (conf *evictSlowTrendSchedulerConfig) markCandidateRecovered() {
+// isInflightRegion checks whether the region still has the in-flight operator
+// found on prepare, the region is forgotten once the operator finishes.
+func (conf *evictSlowTrendSchedulerConfig) isInflightRegion(regionID uint64) bool {
 	conf.Lock()
 	defer conf.Unlock()
-	if conf.lastEvictCandidate != (slowCandidate{}) {
-		conf.lastEvictCandidate.recoverTS = time.Now()
+	if _, ok := conf.inflightRegions[regionID]; !ok {
+		return false
+	}
+	if conf.hasOperator != nil && conf.hasOperator(regionID) {
+		return true
 	}
+	delete(conf.inflightRegions, regionID)
+	return false
 }
 
-func (conf *evictSlowTrendSchedulerConfig) setStoreAndPersist(id uint64) error {
-	conf.Lock()
-	defer conf.Unlock()
-	conf.EvictedStores = []uint64{id}
-	return conf.persistLocked()
+// inflightRegionFilter rejects the regions with the in-flight operators found
+// on prepare, so that no duplicate operator is issued for them.
+type inflightRegionFilter struct {
+	conf *evictSlowTrendSchedulerConfig
 }
 
-func (conf *evictSlowTrendSchedulerConfig) clearAndPersist(cluster sche.SchedulerCluster) (oldID uint64, err error) {
-	oldID = conf.evictedStore()
-	if oldID == 0 {
-		return
-	}
-	address := "?"
-	store := cluster.GetStore(oldID)
-	if store != nil {
-		address = store.GetAddress()
+// Select implements filter.RegionFilter.
+func (f *inflightRegionFilter) Select(region *core.RegionInfo) *plan.Status {
+	if f.conf.isInflightRegion(region.GetID()) {
+		storeSlowTrendActionStatusGauge.WithLabelValues("evict", "skip_inflight_region").Inc()
+		return inflightRegionStatus
 	}
-	storeSlowTrendEvictedStatusGauge.WithLabelValues(address, strconv.FormatUint(oldID, 10)).Set(0)
-	conf.Lock()
+	return plan.NewStatus(plan.StatusOK)
+}
+
+func (conf *evictSlowTrendSchedulerConfig) getKeyRangesByID(id uint64) []core.KeyRange {
+	if conf.evictedStore() != id {
+		return nil
+	}
+	conf.RLock()
+	defer conf.RUnlock()
+	if len(conf.KeyRanges) > 0 {
+		return conf.KeyRanges
+	}
+	return []core.KeyRange{core.NewKeyRange("", "")}
+}
+
+func (conf *evictSlowTrendSchedulerConfig) getLogger() *zap.Logger {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.logger == nil {
+		return log.L()
+	}
+	return conf.logger
+}
+
+// logRoutine logs the routine state transitions, which are only logged at info
+// level if the log level is verbose.
+func (conf *evictSlowTrendSchedulerConfig) logRoutine(msg string, fields ...zap.Field) {
+	conf.RLock()
+	verbose := conf.LogLevel == slowTrendLogLevelVerbose
+	conf.RUnlock()
+	if verbose {
+		conf.getLogger().Info(msg, fields...)
+	} else {
+		conf.getLogger().Debug(msg, fields...)
+	}
+}
+
+// recordScan takes the snapshot of the slow trends of the eligible stores.
+func (conf *evictSlowTrendSchedulerConfig) recordScan(stores []*core.StoreInfo) {
+	cfg := conf.Clone()
+	scan := slowTrendScan{ScanTS: cfg.now()}
+	// Take the snapshot of the slow trends once, and count the slower/faster
+	// stores by the sorted `CauseValue`s rather than comparing each pair of
+	// stores, which is too costly in a large cluster.
+	trends := make(map[uint64]*pdpb.SlowTrend, len(stores))
+	causeValues := make([]float64, 0, len(stores))
+	for _, store := range stores {
+		if !isStoreEligible(store, cfg.ConsiderPreparingStores) {
+			continue
+		}
+		slowTrend := store.GetSlowTrend()
+		trends[store.GetID()] = slowTrend
+		if slowTrend != nil && slowTrend.CauseValue > cfg.ComparisonEpsilon {
+			causeValues = append(causeValues, slowTrend.CauseValue)
+		}
+	}
+	sort.Float64s(causeValues)
+	for _, store := range stores {
+		slowTrend, ok := trends[store.GetID()]
+		if !ok {
+			continue
+		}
+		result := slowTrendScanResult{
+			StoreID: store.GetID(),
+			Matched: matchSlowTrendPattern(store, cfg.ResultFieldsOptional, cfg.ComparisonEpsilon),
+		}
+		if slowTrend != nil {
+			result.CauseRate = slowTrend.CauseRate
+			result.ResultRate = slowTrend.ResultRate
+			result.CauseValue = slowTrend.CauseValue
+			result.ResultValue = slowTrend.ResultValue
+			// Same as `countSlowerThanStores`, the store itself is never counted.
+			result.SlowerThanCount = sort.Search(len(causeValues), func(i int) bool {
+				return slowTrend.CauseValue-causeValues[i] <= cfg.ComparisonEpsilon
+			})
+			// Same as `countFasterThanStores`, excluding the store itself.
+			if slowTrend.CauseValue > cfg.ComparisonEpsilon {
+				result.FasterThanCount = len(causeValues) - sort.Search(len(causeValues), func(i int) bool {
+					return slowTrend.CauseValue <= causeValues[i]*1.1
+				}) - 1
+			}
+		}
+		scan.Stores = append(scan.Stores, result)
+	}
+	conf.Lock()
+	defer conf.Unlock()
+	conf.lastScan = scan
+}
+
+func (conf *evictSlowTrendSchedulerConfig) getLastScan() slowTrendScan {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.lastScan
+}
+
+// recordCaptureSnapshot records the slow trend of the candidate just captured.
+func (conf *evictSlowTrendSchedulerConfig) recordCaptureSnapshot(store *core.StoreInfo) {
+	conf.Lock()
+	defer conf.Unlock()
+	conf.pendingHistory = &slowTrendHistoryEntry{
+		StoreID: store.GetID(),
+		Capture: newSlowTrendSnapshot(store, conf.now()),
+	}
+}
+
+// recordEvictionHistory adds an entry to the history for the store just
+// evicted, along with the slow trend recorded when it was captured.
+func (conf *evictSlowTrendSchedulerConfig) recordEvictionHistory(store *core.StoreInfo) {
+	conf.Lock()
+	defer conf.Unlock()
+	entry := slowTrendHistoryEntry{StoreID: store.GetID()}
+	if conf.pendingHistory != nil && conf.pendingHistory.StoreID == store.GetID() {
+		entry = *conf.pendingHistory
+	}
+	entry.Evict = newSlowTrendSnapshot(store, conf.now())
+	conf.pendingHistory = nil
+	conf.history = append(conf.history, entry)
+	if len(conf.history) > slowTrendHistorySize {
+		conf.history = conf.history[len(conf.history)-slowTrendHistorySize:]
+	}
+}
+
+// recordRecoveryHistory completes the latest ongoing entry of the store with
+// the slow trend when the eviction ends.
+func (conf *evictSlowTrendSchedulerConfig) recordRecoveryHistory(store *core.StoreInfo, storeID uint64, reason evictCleanupReason) {
+	conf.Lock()
+	defer conf.Unlock()
+	for i := len(conf.history) - 1; i >= 0; i-- {
+		entry := &conf.history[i]
+		if entry.StoreID != storeID || entry.Reason != "" {
+			continue
+		}
+		entry.Recover = newSlowTrendSnapshot(store, conf.now())
+		entry.Reason = string(reason)
+		return
+	}
+}
+
+func (conf *evictSlowTrendSchedulerConfig) getHistory() []slowTrendHistoryEntry {
+	conf.RLock()
+	defer conf.RUnlock()
+	history := make([]slowTrendHistoryEntry, len(conf.history))
+	copy(history, conf.history)
+	return history
+}
+
+func (conf *evictSlowTrendSchedulerConfig) webhookURL() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.WebhookURL
+}
+
+func (conf *evictSlowTrendSchedulerConfig) hasEvictedStores() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return len(conf.EvictedStores) > 0
+}
+
+func (conf *evictSlowTrendSchedulerConfig) isEvicted(id uint64) bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	for _, storeID := range conf.EvictedStores {
+		if storeID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (conf *evictSlowTrendSchedulerConfig) evictedStore() uint64 {
+	if !conf.hasEvictedStores() {
+		return 0
+	}
+	conf.RLock()
+	defer conf.RUnlock()
+	// If a candidate passes all checks and proved to be slow, it will be
+	// recorded in `conf.EvictStores`, and `conf.LastEvictCandidate` will record
+	// the captured timestamp of this store.
+	return conf.EvictedStores[0]
+}
+
+func (conf *evictSlowTrendSchedulerConfig) evictedTS() time.Time {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EvictedTS
+}
+
+func (conf *evictSlowTrendSchedulerConfig) candidate() uint64 {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EvictCandidate.StoreID
+}
+
+func (conf *evictSlowTrendSchedulerConfig) captureTS() time.Time {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EvictCandidate.CaptureTS
+}
+
+func (conf *evictSlowTrendSchedulerConfig) candidateCapturedSecs() uint64 {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.secsSince(conf.EvictCandidate.CaptureTS)
+}
+
+// secsSince returns the duration gap since the given state timestamp, unit: s.
+func (conf *evictSlowTrendSchedulerConfig) secsSince(startTS time.Time) uint64 {
+	return durationBetweenAsSecs(startTS, conf.now())
+}
+
+func (conf *evictSlowTrendSchedulerConfig) lastCapturedCandidate() *slowCandidate {
+	conf.RLock()
+	defer conf.RUnlock()
+	return &conf.LastEvictCandidate
+}
+
+func (conf *evictSlowTrendSchedulerConfig) lastCandidateCapturedSecs() uint64 {
+	return conf.secsSince(conf.LastEvictCandidate.CaptureTS)
+}
+
+// recoveryDurationGapLocked returns the recovery gap of the last captured
+// candidate, the per-store override takes precedence over the global one.
+func (conf *evictSlowTrendSchedulerConfig) recoveryDurationGapLocked() uint64 {
+	if gap, ok := conf.PerStoreRecoveryGap[conf.LastEvictCandidate.StoreID]; ok {
+		return gap
+	}
+	return conf.RecoveryDurationGap
+}
+
+// readyForRecovery checks whether the last cpatured candidate is ready for recovery.
+func (conf *evictSlowTrendSchedulerConfig) readyForRecovery() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	recoveryDurationGap := conf.recoveryDurationGapLocked()
+	failpoint.Inject("transientRecoveryGap", func() {
+		recoveryDurationGap = 0
+	})
+	if conf.flapHoldingLocked() {
+		return false
+	}
+	// The store which has looked fast for enough scans is recovered without
+	// waiting for the time-based criteria.
+	if conf.RecoveryFastScans > 0 && conf.recoveryFastTicks >= conf.RecoveryFastScans {
+		return true
+	}
+	if _, scaledGap, ok := conf.scaledRecoveryGapLocked(); ok && conf.now().Sub(conf.recoveryFastSince) < scaledGap {
+		return false
+	}
+	// The capture time is unknown, do not block the recovery.
+	if conf.LastEvictCandidate.CaptureTS.IsZero() {
+		return true
+	}
+	return conf.lastCandidateCapturedSecs() >= recoveryDurationGap
+}
+
+// scaledRecoveryGapLocked returns how long the last captured candidate had
+// been slow before it began to look fast, and how long it should keep fast,
+// which is scaled by the former. It returns false if the gap is not scaled.
+func (conf *evictSlowTrendSchedulerConfig) scaledRecoveryGapLocked() (slowDuration, scaledGap time.Duration, ok bool) {
+	if conf.RecoveryGapScalingFactor <= 0 || conf.recoveryFastSince.IsZero() || conf.LastEvictCandidate.CaptureTS.IsZero() {
+		return 0, 0, false
+	}
+	// The longer the store had been slow, the longer it should keep fast. The
+	// capture time may be persisted by another leader with a skewed clock.
+	slowDuration = max(conf.recoveryFastSince.Sub(conf.LastEvictCandidate.CaptureTS), 0)
+	scaledGap = time.Duration(float64(slowDuration) * conf.RecoveryGapScalingFactor)
+	if maxGap := time.Duration(conf.MaxRecoveryDurationGap) * time.Second; maxGap > 0 && scaledGap > maxGap {
+		scaledGap = maxGap
+	}
+	return slowDuration, scaledGap, true
+}
+
+// recoveryProgress returns how close the last captured candidate is to the
+// recovery gap, 1.0 means the gap has been reached.
+func (conf *evictSlowTrendSchedulerConfig) recoveryProgress() float64 {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.recoveryProgressLocked()
+}
+
+func (conf *evictSlowTrendSchedulerConfig) recoveryProgressLocked() float64 {
+	if conf.LastEvictCandidate.CaptureTS.IsZero() {
+		return 1.0
+	}
+	gap := time.Duration(conf.recoveryDurationGapLocked()) * time.Second
+	if slowDuration, scaledGap, ok := conf.scaledRecoveryGapLocked(); ok {
+		gap = max(gap, slowDuration+scaledGap)
+	}
+	if gap <= 0 {
+		return 1.0
+	}
+	elapsed := time.Duration(conf.lastCandidateCapturedSecs()) * time.Second
+	return math.Min(1.0, elapsed.Seconds()/gap.Seconds())
+}
+
+// inEvictSettlePeriod checks whether the eviction started within
+// `EvictSettlePeriod`, during which the recovery is not evaluated.
+func (conf *evictSlowTrendSchedulerConfig) inEvictSettlePeriod() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.EvictSettlePeriod == 0 || conf.EvictedTS.IsZero() {
+		return false
+	}
+	return conf.now().Sub(conf.EvictedTS) < time.Duration(conf.EvictSettlePeriod)*time.Second
+}
+
+// observeRecoveryTick records whether the evicted store looks fast in this
+// tick, and returns whether it has looked fast for `RecoveryStabilityWindow`
+// consecutive ticks.
+func (conf *evictSlowTrendSchedulerConfig) observeRecoveryTick(fast bool) bool {
+	conf.Lock()
+	defer conf.Unlock()
+	if !fast {
+		conf.recoveryFastTicks = 0
+		conf.recoveryFastSince = time.Time{}
+		return false
+	}
+	if conf.recoveryFastTicks == 0 {
+		conf.recoveryFastSince = conf.now()
+	}
+	conf.recoveryFastTicks++
+	return conf.recoveryFastTicks >= conf.RecoveryStabilityWindow
+}
+
+func (conf *evictSlowTrendSchedulerConfig) captureCandidate(id uint64) {
+	conf.Lock()
+	defer conf.Unlock()
+	conf.EvictCandidate = slowCandidate{
+		StoreID:   id,
+		CaptureTS: conf.now(),
+		RecoverTS: conf.now(),
+		Samples:   1,
+		SampleTS:  conf.now(),
+	}
+	conf.LastActiveTS = conf.now()
+	if conf.LastEvictCandidate == (slowCandidate{}) {
+		conf.LastEvictCandidate = conf.EvictCandidate
+	}
+}
+
+// candidateConfirmed checks whether the candidate has been observed as slow by
+// enough samples.
+func (conf *evictSlowTrendSchedulerConfig) candidateConfirmed() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EvictCandidate.Samples >= conf.ConfirmationSamples
+}
+
+// candidateSampleDue checks whether it's time to take the next sample of the
+// candidate.
+func (conf *evictSlowTrendSchedulerConfig) candidateSampleDue() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	interval := time.Duration(conf.ConfirmationInterval) * time.Second
+	return conf.now().Sub(conf.EvictCandidate.SampleTS) >= interval
+}
+
+func (conf *evictSlowTrendSchedulerConfig) addCandidateSample() {
+	conf.Lock()
+	defer conf.Unlock()
+	conf.EvictCandidate.Samples++
+	conf.EvictCandidate.SampleTS = conf.now()
+}
+
+// dropEvictedCandidate drops the candidate if it has been evicted already, and
+// returns the dropped store ID.
+func (conf *evictSlowTrendSchedulerConfig) dropEvictedCandidate() uint64 {
+	conf.Lock()
+	defer conf.Unlock()
+	id := conf.EvictCandidate.StoreID
+	if id == 0 {
+		return 0
+	}
+	for _, evictedID := range conf.EvictedStores {
+		if evictedID == id {
+			conf.EvictCandidate = slowCandidate{}
+			return id
+		}
+	}
+	return 0
+}
+
+func (conf *evictSlowTrendSchedulerConfig) popCandidate(updLast bool) uint64 {
+	conf.Lock()
+	defer conf.Unlock()
+	id := conf.EvictCandidate.StoreID
+	if updLast {
+		conf.LastEvictCandidate = conf.EvictCandidate
+	}
+	conf.EvictCandidate = slowCandidate{}
+	return id
+}
+
+func (conf *evictSlowTrendSchedulerConfig) markCandidateRecovered() {
+	conf.Lock()
+	defer conf.Unlock()
+	if conf.LastEvictCandidate != (slowCandidate{}) {
+		conf.LastEvictCandidate.RecoverTS = conf.now()
+	}
+}
+
+func (conf *evictSlowTrendSchedulerConfig) setStoreAndPersist(id uint64) error {
+	conf.Lock()
+	defer conf.Unlock()
+	evictedStores, evictedTS, recentEvictions := conf.EvictedStores, conf.EvictedTS, conf.RecentEvictions
+	fastTicks, fastSince, evictedReasons := conf.recoveryFastTicks, conf.recoveryFastSince, conf.EvictedReasons
+	lastActiveTS := conf.LastActiveTS
+	conf.EvictedStores = []uint64{id}
+	conf.EvictedReasons = make(map[uint64]string)
+	conf.EvictedTS = conf.now()
+	conf.LastActiveTS = conf.now()
+	conf.recoveryFastTicks, conf.recoveryFastSince = 0, time.Time{}
+	conf.recordEvictionLocked()
+	conf.countEvictionLocked(id)
+	conf.recordStoreEvictionLocked(id)
+	return conf.persistDecisionLocked(func() {
+		conf.EvictedStores, conf.EvictedTS, conf.RecentEvictions = evictedStores, evictedTS, recentEvictions
+		conf.recoveryFastTicks, conf.recoveryFastSince, conf.EvictedReasons = fastTicks, fastSince, evictedReasons
+		conf.LastActiveTS = lastActiveTS
+		if conf.EvictionCounts[id]--; conf.EvictionCounts[id] == 0 {
+			delete(conf.EvictionCounts, id)
+		}
+		if history := conf.evictionHistory[id]; len(history) > 0 {
+			conf.evictionHistory[id] = history[:len(history)-1]
+		}
+	})
+}
+
+// recordEvictionLocked records the timestamp of an eviction and drops the
+// ones out of the budget window.
+func (conf *evictSlowTrendSchedulerConfig) recordEvictionLocked() {
+	now := conf.now()
+	window := time.Duration(conf.EvictionBudgetWindow) * time.Second
+	recentEvictions := make([]time.Time, 0, len(conf.RecentEvictions)+1)
+	for _, ts := range conf.RecentEvictions {
+		if now.Sub(ts) < window {
+			recentEvictions = append(recentEvictions, ts)
+		}
+	}
+	conf.RecentEvictions = append(recentEvictions, now)
+}
+
+// countEvictionLocked increases the eviction count of the store, and prunes the
+// counts of the removed stores.
+func (conf *evictSlowTrendSchedulerConfig) countEvictionLocked(id uint64) {
+	if conf.EvictionCounts == nil {
+		conf.EvictionCounts = make(map[uint64]uint64)
+	}
+	if conf.cluster != nil {
+		for storeID := range conf.EvictionCounts {
+			if store := conf.cluster.GetStore(storeID); store == nil || store.IsRemoved() {
+				delete(conf.EvictionCounts, storeID)
+			}
+		}
+	}
+	conf.EvictionCounts[id]++
+}
+
+// recordStoreEvictionLocked records the timestamp of an eviction of the store,
+// and drops the ones out of the flap window.
+func (conf *evictSlowTrendSchedulerConfig) recordStoreEvictionLocked(id uint64) {
+	if conf.evictionHistory == nil {
+		conf.evictionHistory = make(map[uint64][]time.Time)
+	}
+	now := conf.now()
+	window := time.Duration(conf.FlapWindow) * time.Second
+	history := make([]time.Time, 0, len(conf.evictionHistory[id])+1)
+	for _, ts := range conf.evictionHistory[id] {
+		if now.Sub(ts) < window {
+			history = append(history, ts)
+		}
+	}
+	conf.evictionHistory[id] = append(history, now)
+}
+
+// isFlappingLocked checks whether the store has been evicted for `FlapThreshold`
+// times within `FlapWindow` before its latest eviction.
+func (conf *evictSlowTrendSchedulerConfig) isFlappingLocked(id uint64) bool {
+	if conf.FlapThreshold == 0 {
+		return false
+	}
+	return uint64(len(conf.evictionHistory[id])) >= conf.FlapThreshold
+}
+
+// isFlapping checks whether the evicted store is flapping.
+func (conf *evictSlowTrendSchedulerConfig) isFlapping(id uint64) bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.isFlappingLocked(id)
+}
+
+// flapHoldingLocked checks whether the evicted store is flapping and should be
+// held evicted for `FlapHoldDuration` since its latest eviction.
+func (conf *evictSlowTrendSchedulerConfig) flapHoldingLocked() bool {
+	if len(conf.EvictedStores) == 0 || !conf.isFlappingLocked(conf.EvictedStores[0]) {
+		return false
+	}
+	history := conf.evictionHistory[conf.EvictedStores[0]]
+	return conf.now().Sub(history[len(history)-1]) < time.Duration(conf.FlapHoldDuration)*time.Second
+}
+
+// trendFirstSeen is the time when the store was seen reporting the slow trend
+// first, and the start time of the store then, to tell whether it restarted.
+type trendFirstSeen struct {
+	seenAt    time.Time
+	startTime time.Time
+}
+
+// updateTrendFirstSeen records the time when each store was seen reporting the
+// slow trend first, the record is reset once the store stops reporting it or
+// restarts.
+func (conf *evictSlowTrendSchedulerConfig) updateTrendFirstSeen(stores []*core.StoreInfo) {
+	conf.Lock()
+	defer conf.Unlock()
+	if conf.trendFirstSeen == nil {
+		conf.trendFirstSeen = make(map[uint64]trendFirstSeen)
+	}
+	seen := make(map[uint64]struct{}, len(stores))
+	for _, store := range stores {
+		if store.GetSlowTrend() == nil {
+			continue
+		}
+		seen[store.GetID()] = struct{}{}
+		if record, ok := conf.trendFirstSeen[store.GetID()]; ok && record.startTime.Equal(store.GetStartTime()) {
+			continue
+		}
+		conf.trendFirstSeen[store.GetID()] = trendFirstSeen{seenAt: conf.now(), startTime: store.GetStartTime()}
+	}
+	for storeID := range conf.trendFirstSeen {
+		if _, ok := seen[storeID]; !ok {
+			delete(conf.trendFirstSeen, storeID)
+		}
+	}
+}
+
+// trendMature checks whether the store has been reporting the slow trend for
+// `MinTrendDataAge`.
+func (conf *evictSlowTrendSchedulerConfig) trendMature(storeID uint64) bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.MinTrendDataAge == 0 {
+		return true
+	}
+	record, ok := conf.trendFirstSeen[storeID]
+	return ok && conf.now().Sub(record.seenAt) >= time.Duration(conf.MinTrendDataAge)*time.Second
+}
+
+// maskImmatureTrends returns the stores with the immature slow trends dropped,
+// so that they are regarded as no data.
+func (conf *evictSlowTrendSchedulerConfig) maskImmatureTrends(stores []*core.StoreInfo) []*core.StoreInfo {
+	conf.RLock()
+	minAge := conf.MinTrendDataAge
+	conf.RUnlock()
+	if minAge == 0 {
+		return stores
+	}
+	masked := make([]*core.StoreInfo, 0, len(stores))
+	for _, store := range stores {
+		if store.GetSlowTrend() == nil || conf.trendMature(store.GetID()) {
+			masked = append(masked, store)
+			continue
+		}
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "skip_immature_trend").Inc()
+		stats := typeutil.DeepClone(store.GetStoreStats(), core.StoreStatsFactory)
+		stats.SlowTrend = nil
+		// Do not modify the stats shared with the store in the cluster.
+		masked = append(masked, store.Clone(core.SetNewStoreStats(stats)))
+	}
+	return masked
+}
+
+// recordCandidateCancel records the cancel of the candidate, and prunes the
+// records out of the cooldown.
+func (conf *evictSlowTrendSchedulerConfig) recordCandidateCancel(id uint64) {
+	conf.Lock()
+	defer conf.Unlock()
+	if conf.candidateCanceledAt == nil {
+		conf.candidateCanceledAt = make(map[uint64]time.Time)
+	}
+	now, cooldown := conf.now(), time.Duration(conf.CandidateCancelCooldown)*time.Second
+	for storeID, ts := range conf.candidateCanceledAt {
+		if now.Sub(ts) >= cooldown {
+			delete(conf.candidateCanceledAt, storeID)
+		}
+	}
+	conf.candidateCanceledAt[id] = now
+}
+
+// inCandidateCancelCooldown checks whether the store is in the cooldown after
+// its candidate was canceled.
+func (conf *evictSlowTrendSchedulerConfig) inCandidateCancelCooldown(id uint64) bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.CandidateCancelCooldown == 0 {
+		return false
+	}
+	ts, ok := conf.candidateCanceledAt[id]
+	return ok && conf.now().Sub(ts) < time.Duration(conf.CandidateCancelCooldown)*time.Second
+}
+
+// evictBatchSize returns the batch size of evicting the leaders, it ramps up
+// from `EvictRampInitialBatch` to `EvictLeaderBatchSize` since the eviction.
+func (conf *evictSlowTrendSchedulerConfig) evictBatchSize() int {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.EvictRampInitialBatch == 0 {
+		return EvictLeaderBatchSize
+	}
+	batchSize := conf.EvictRampInitialBatch
+	if conf.EvictRampInterval > 0 {
+		batchSize += conf.EvictRampStep * (conf.secsSince(conf.EvictedTS) / conf.EvictRampInterval)
+	}
+	if batchSize > EvictLeaderBatchSize {
+		return EvictLeaderBatchSize
+	}
+	return int(batchSize)
+}
+
+func (conf *evictSlowTrendSchedulerConfig) evictOperatorLimit() uint64 {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EvictOperatorLimit
+}
+
+func (conf *evictSlowTrendSchedulerConfig) isSoftEvict() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.SoftEvict
+}
+
+// leaderWeightChange is the leader weight of a store changed by the scheduler.
+// It's persisted, so that the original weight can still be restored after the
+// leader changes.
+type leaderWeightChange struct {
+	StoreID uint64 `json:"store-id"`
+	// OriginalWeight is the leader weight before it's changed.
+	OriginalWeight float64 `json:"original-weight"`
+	// Weight is the leader weight set by the scheduler. If the store has a
+	// different one, it has been changed by others, e.g., by the API, and it's
+	// not overwritten by the original one.
+	Weight float64 `json:"weight"`
+}
+
+func (conf *evictSlowTrendSchedulerConfig) softEvictedWeight() leaderWeightChange {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.SoftEvictedWeight
+}
+
+// isSoftEvicted checks whether the store is soft evicted, i.e., its leader
+// weight is lowered instead of marking it as evicted in the cluster.
+func (conf *evictSlowTrendSchedulerConfig) isSoftEvicted(id uint64) bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return id != 0 && conf.SoftEvictedWeight.StoreID == id
+}
+
+// setSoftEvictedWeight persists the leader weight change of the soft evicted
+// store, the zero change means no store is soft evicted.
+func (conf *evictSlowTrendSchedulerConfig) setSoftEvictedWeight(change leaderWeightChange) error {
+	conf.Lock()
+	defer conf.Unlock()
+	prev := conf.SoftEvictedWeight
+	conf.SoftEvictedWeight = change
+	return conf.persistDecisionLocked(func() {
+		conf.SoftEvictedWeight = prev
+	})
+}
+
+// storeReadmission is the state of re-admitting a recovered store. It's
+// persisted, so that the re-admission goes on after the leader changes.
+type storeReadmission struct {
+	StoreID uint64 `json:"store-id"`
+	// OriginalWeight is the leader weight before the re-admission.
+	OriginalWeight float64 `json:"original-weight"`
+	// Stage is the index of the current stage in `ReadmissionStages`.
+	Stage int `json:"stage"`
+	// Weight is the leader weight set by the scheduler in the current stage.
+	// If the store has a different one, it has been changed by others, e.g.,
+	// by the API, and the re-admission is stopped without overwriting it.
+	Weight  float64   `json:"weight"`
+	StartTS time.Time `json:"start-ts"`
+}
+
+func (conf *evictSlowTrendSchedulerConfig) readmissionEnabled() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return len(conf.ReadmissionStages) > 0
+}
+
+func (conf *evictSlowTrendSchedulerConfig) readmission() storeReadmission {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.Readmission
+}
+
+// setReadmission persists the state of re-admitting the store, the zero state
+// means no store is being re-admitted.
+func (conf *evictSlowTrendSchedulerConfig) setReadmission(r storeReadmission) error {
+	conf.Lock()
+	defer conf.Unlock()
+	prev := conf.Readmission
+	conf.Readmission = r
+	return conf.persistDecisionLocked(func() {
+		conf.Readmission = prev
+	})
+}
+
+// readmissionStage returns the current stage of re-admitting the store and the
+// leader weight of it, done means the store should be fully restored.
+func (conf *evictSlowTrendSchedulerConfig) readmissionStage(r storeReadmission) (stage int, weight float64, done bool) {
+	conf.RLock()
+	defer conf.RUnlock()
+	stage = len(conf.ReadmissionStages)
+	if conf.ReadmissionStageDuration > 0 {
+		stage = int(uint64(conf.now().Sub(r.StartTS).Seconds()) / conf.ReadmissionStageDuration)
+	}
+	if stage >= len(conf.ReadmissionStages) {
+		return stage, r.OriginalWeight, true
+	}
+	return stage, r.OriginalWeight * conf.ReadmissionStages[stage], false
+}
+
+// hasEvictionBudget checks whether the number of evictions within the budget
+// window has not reached the limit.
+func (conf *evictSlowTrendSchedulerConfig) hasEvictionBudget() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.MaxEvictionsPerWindow == 0 {
+		return true
+	}
+	now := conf.now()
+	window := time.Duration(conf.EvictionBudgetWindow) * time.Second
+	var count uint64
+	for _, ts := range conf.RecentEvictions {
+		if now.Sub(ts) < window {
+			count++
+		}
+	}
+	return count < conf.MaxEvictionsPerWindow
+}
+
+// updateWriteStallStates records when the write stall of each store started,
+// the stores without write stall are dropped.
+func (conf *evictSlowTrendSchedulerConfig) updateWriteStallStates(stores []*core.StoreInfo) {
+	conf.Lock()
+	defer conf.Unlock()
+	if !conf.EvictOnWriteStall {
+		conf.writeStallSince = make(map[uint64]time.Time)
+		return
+	}
+	now := conf.now()
+	writeStallSince := make(map[uint64]time.Time)
+	for _, store := range stores {
+		if !store.IsBusy() {
+			continue
+		}
+		since, ok := conf.writeStallSince[store.GetID()]
+		if !ok {
+			since = now
+		}
+		writeStallSince[store.GetID()] = since
+	}
+	conf.writeStallSince = writeStallSince
+}
+
+// hasSustainedWriteStall checks whether the write stall of the store has lasted
+// for `WriteStallDuration`.
+func (conf *evictSlowTrendSchedulerConfig) hasSustainedWriteStall(storeID uint64) bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	if !conf.EvictOnWriteStall {
+		return false
+	}
+	since, ok := conf.writeStallSince[storeID]
+	if !ok {
+		return false
+	}
+	return conf.now().Sub(since) >= time.Duration(conf.WriteStallDuration)*time.Second
+}
+
+// updateConfidences raises the confidence of the stores matching the slow trend
+// pattern, and decays the others.
+func (conf *evictSlowTrendSchedulerConfig) updateConfidences(stores []*core.StoreInfo) {
+	conf.Lock()
+	defer conf.Unlock()
+	confidences := make(map[uint64]float64)
+	if conf.ConfidenceThreshold <= 0 {
+		conf.Confidences = confidences
+		return
+	}
+	for _, store := range stores {
+		if !isStoreEligible(store, conf.ConsiderPreparingStores) {
+			continue
+		}
+		confidence := conf.Confidences[store.GetID()]
+		if matchSlowTrendPattern(store, conf.ResultFieldsOptional, conf.ComparisonEpsilon) {
+			confidence += 1
+		} else {
+			confidence = math.Max(confidence-conf.ConfidenceDecay, 0)
+		}
+		if confidence > 0 {
+			confidences[store.GetID()] = confidence
+		}
+	}
+	conf.Confidences = confidences
+}
+
+// isConfident checks whether the confidence of the store reaches the threshold.
+func (conf *evictSlowTrendSchedulerConfig) isConfident(storeID uint64) bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.ConfidenceThreshold <= 0 {
+		return true
+	}
+	return conf.Confidences[storeID] >= conf.ConfidenceThreshold
+}
+
+func (conf *evictSlowTrendSchedulerConfig) isLongitudinal() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.DetectionMode == slowTrendDetectionModeLongitudinal
+}
+
+// observeBaseline returns the ratio of the `CauseValue` to the baseline of the
+// store, and then rolls the baseline with the value. The first observation
+// only initializes the baseline and returns 0.
+func (conf *evictSlowTrendSchedulerConfig) observeBaseline(storeID uint64, causeValue float64) float64 {
+	conf.Lock()
+	defer conf.Unlock()
+	baseline, ok := conf.causeValueBaselines[storeID]
+	if !ok {
+		conf.causeValueBaselines[storeID] = causeValue
+		return 0
+	}
+	var ratio float64
+	if baseline > alterEpsilon {
+		ratio = causeValue / baseline
+	}
+	conf.causeValueBaselines[storeID] = baseline*(1-baselineSmoothingFactor) + causeValue*baselineSmoothingFactor
+	return ratio
+}
+
+// regressedAgainstBaseline checks whether the `CauseValue` of the store has
+// regressed against its baseline.
+func (conf *evictSlowTrendSchedulerConfig) regressedAgainstBaseline(store *core.StoreInfo) bool {
+	if store == nil || store.GetSlowTrend() == nil {
+		return false
+	}
+	conf.RLock()
+	defer conf.RUnlock()
+	baseline, ok := conf.causeValueBaselines[store.GetID()]
+	if !ok || baseline <= alterEpsilon {
+		return false
+	}
+	return store.GetSlowTrend().CauseValue/baseline >= conf.BaselineRegressionRatio
+}
+
+func (conf *evictSlowTrendSchedulerConfig) pause(duration time.Duration) error {
+	conf.Lock()
+	defer conf.Unlock()
+	old := conf.PausedUntil
+	conf.PausedUntil = conf.now().Add(duration)
+	if err := conf.persistLocked(); err != nil {
+		conf.PausedUntil = old
+		return err
+	}
+	return nil
+}
+
+func (conf *evictSlowTrendSchedulerConfig) resume() error {
+	conf.Lock()
+	defer conf.Unlock()
+	if conf.PausedUntil.IsZero() {
+		return nil
+	}
+	old := conf.PausedUntil
+	conf.PausedUntil = time.Time{}
+	return conf.persistDecisionLocked(func() {
+		conf.PausedUntil = old
+	})
+}
+
+// inMaintenanceWindow checks whether now is in the daily maintenance window.
+func (conf *evictSlowTrendSchedulerConfig) inMaintenanceWindow() bool {
+	conf.RLock()
+	start, end, timezone := conf.MaintenanceWindowStart, conf.MaintenanceWindowEnd, conf.MaintenanceWindowTimezone
+	conf.RUnlock()
+	if start == "" && end == "" {
+		return false
+	}
+	startMinute, endMinute, loc, err := parseMaintenanceWindow(start, end, timezone)
+	if err != nil {
+		log.Warn("evict-slow-trend-scheduler ignore invalid maintenance window", errs.ZapError(err))
+		return false
+	}
+	now := conf.now().In(loc)
+	minute := now.Hour()*60 + now.Minute()
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	// The window crosses midnight.
+	return minute >= startMinute || minute < endMinute
+}
+
+// parseMaintenanceWindow parses the maintenance window, and returns the minutes
+// of the start and end since midnight.
+func parseMaintenanceWindow(start, end, timezone string) (startMinute, endMinute int, loc *time.Location, err error) {
+	loc = time.UTC
+	if timezone != "" {
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return 0, 0, nil, errors.Errorf("invalid maintenance window start %q", start)
+	}
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return 0, 0, nil, errors.Errorf("invalid maintenance window end %q", end)
+	}
+	return startTime.Hour()*60 + startTime.Minute(), endTime.Hour()*60 + endTime.Minute(), loc, nil
+}
+
+// isPaused checks whether the scheduler is paused, the expired deadline will
+// be cleared.
+func (conf *evictSlowTrendSchedulerConfig) isPaused() bool {
+	conf.RLock()
+	pausedUntil := conf.PausedUntil
+	conf.RUnlock()
+	if pausedUntil.IsZero() {
+		return false
+	}
+	if conf.now().Before(pausedUntil) {
+		return true
+	}
+	if err := conf.resume(); err != nil {
+		log.Info("evict-slow-trend-scheduler persist config failed", errs.ZapError(err))
+	}
+	return false
+}
+
+func (conf *evictSlowTrendSchedulerConfig) clearAndPersist(cluster sche.SchedulerCluster) (oldID uint64, err error) {
+	oldID = conf.evictedStore()
+	if oldID == 0 {
+		return
+	}
+	address := "?"
+	store := cluster.GetStore(oldID)
+	if store != nil {
+		address = store.GetAddress()
+	}
+	storeSlowTrendEvictedStatusGauge.WithLabelValues(address, strconv.FormatUint(oldID, 10)).Set(0)
+	clearRecoveryProgress(oldID)
+	return oldID, conf.clearStoresAndPersist()
+}
+
+func (conf *evictSlowTrendSchedulerConfig) clearStoresAndPersist() error {
+	conf.Lock()
+	defer conf.Unlock()
+	evictedStores, fastTicks, fastSince := conf.EvictedStores, conf.recoveryFastTicks, conf.recoveryFastSince
+	evictedReasons := conf.EvictedReasons
+	conf.EvictedStores, conf.EvictedReasons = []uint64{}, make(map[uint64]string)
+	conf.recoveryFastTicks, conf.recoveryFastSince = 0, time.Time{}
+	return conf.persistDecisionLocked(func() {
+		conf.EvictedStores, conf.recoveryFastTicks, conf.recoveryFastSince = evictedStores, fastTicks, fastSince
+		conf.EvictedReasons = evictedReasons
+	})
+}
+
+// removeStoreAndPersist removes the store from the evicted stores, it returns
+// false if the store is not evicted.
+func (conf *evictSlowTrendSchedulerConfig) removeStoreAndPersist(id uint64) (bool, error) {
+	conf.Lock()
+	defer conf.Unlock()
+	evictedStores := make([]uint64, 0, len(conf.EvictedStores))
+	for _, storeID := range conf.EvictedStores {
+		if storeID != id {
+			evictedStores = append(evictedStores, storeID)
+		}
+	}
+	if len(evictedStores) == len(conf.EvictedStores) {
+		return false, nil
+	}
+	oldEvictedStores, fastTicks, fastSince := conf.EvictedStores, conf.recoveryFastTicks, conf.recoveryFastSince
+	reason, annotated := conf.EvictedReasons[id]
+	conf.EvictedStores = evictedStores
+	delete(conf.EvictedReasons, id)
+	if len(evictedStores) == 0 {
+		conf.recoveryFastTicks, conf.recoveryFastSince = 0, time.Time{}
+	}
+	return true, conf.persistDecisionLocked(func() {
+		conf.EvictedStores, conf.recoveryFastTicks, conf.recoveryFastSince = oldEvictedStores, fastTicks, fastSince
+		if annotated {
+			conf.EvictedReasons[id] = reason
+		}
+	})
+}
+
+// idleTooLong checks whether no capture or eviction has happened for
+// `AutoDisableAfterIdle`. The idle time is counted from the first check if
+// the scheduler has never been active.
+func (conf *evictSlowTrendSchedulerConfig) idleTooLong() (bool, time.Duration) {
+	conf.Lock()
+	defer conf.Unlock()
+	if conf.AutoDisableAfterIdle == 0 || conf.autoDisabled {
+		return false, 0
+	}
+	if len(conf.EvictedStores) > 0 || conf.EvictCandidate.StoreID != 0 {
+		return false, 0
+	}
+	if conf.LastActiveTS.IsZero() {
+		conf.LastActiveTS = conf.now()
+		return false, 0
+	}
+	idle := conf.now().Sub(conf.LastActiveTS)
+	return idle >= time.Duration(conf.AutoDisableAfterIdle)*time.Second, idle
+}
+
+// evictedReason returns the reason annotated to the evicted store, it's empty
+// if not annotated.
+func (conf *evictSlowTrendSchedulerConfig) evictedReason(id uint64) string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EvictedReasons[id]
+}
+
+// annotateAndPersist annotates the evicted store with the reason, an empty
+// reason removes the annotation. It returns false if the store is not evicted.
+func (conf *evictSlowTrendSchedulerConfig) annotateAndPersist(id uint64, reason string) (bool, error) {
+	conf.Lock()
 	defer conf.Unlock()
-	conf.EvictedStores = []uint64{}
-	return oldID, conf.persistLocked()
+	evicted := false
+	for _, storeID := range conf.EvictedStores {
+		if storeID == id {
+			evicted = true
+			break
+		}
+	}
+	if !evicted {
+		return false, nil
+	}
+	if conf.EvictedReasons == nil {
+		conf.EvictedReasons = make(map[uint64]string)
+	}
+	oldReason, annotated := conf.EvictedReasons[id]
+	if reason == "" {
+		delete(conf.EvictedReasons, id)
+	} else {
+		conf.EvictedReasons[id] = reason
+	}
+	return true, conf.persistDecisionLocked(func() {
+		if annotated {
+			conf.EvictedReasons[id] = oldReason
+		} else {
+			delete(conf.EvictedReasons, id)
+		}
+	})
 }
 
 type evictSlowTrendHandler struct {
@@ -236,42 +2371,440 @@ func newEvictSlowTrendHandler(config *evictSlowTrendSchedulerConfig) http.Handle
 	router := mux.NewRouter()
 	router.HandleFunc("/config", h.UpdateConfig).Methods(http.MethodPost)
 	router.HandleFunc("/list", h.ListConfig).Methods(http.MethodGet)
+	router.HandleFunc("/pause", h.Pause).Methods(http.MethodPost)
+	router.HandleFunc("/resume", h.Resume).Methods(http.MethodPost)
+	router.HandleFunc("/scan", h.ListScan).Methods(http.MethodGet)
+	router.HandleFunc("/state", h.GetState).Methods(http.MethodGet)
+	router.HandleFunc("/verify", h.VerifyState).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/annotate", h.Annotate).Methods(http.MethodPost)
+	router.HandleFunc("/history", h.ListHistory).Methods(http.MethodGet)
 	return router
 }
 
-func (handler *evictSlowTrendHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
-	var input map[string]any
-	if err := apiutil.ReadJSONRespondError(handler.rd, w, r.Body, &input); err != nil {
-		return
+// GetState gets the lifecycle state of the scheduler, it's one of "idle",
+// "candidate-pending" and "evicting".
+func (handler *evictSlowTrendHandler) GetState(w http.ResponseWriter, _ *http.Request) {
+	handler.rd.JSON(w, http.StatusOK, map[string]string{"state": handler.config.stateName()})
+}
+
+// VerifyState reports the discrepancy between the persisted evicted stores and
+// the stores marked as evicted by slow trend in the cluster. With the POST
+// method, the cluster is repaired to match the persisted state as well.
+func (handler *evictSlowTrendHandler) VerifyState(w http.ResponseWriter, r *http.Request) {
+	cluster := handler.config.cluster
+	if cluster == nil {
+		handler.rd.JSON(w, http.StatusInternalServerError, "the scheduler is not running")
+		return
+	}
+	handler.rd.JSON(w, http.StatusOK, handler.config.verifyState(cluster, r.Method == http.MethodPost))
+}
+
+// Annotate annotates the evicted store with a reason, e.g., why it's evicted,
+// an empty reason removes the annotation.
+func (handler *evictSlowTrendHandler) Annotate(w http.ResponseWriter, r *http.Request) {
+	var input map[string]any
+	if err := apiutil.ReadJSONRespondError(handler.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	storeID, ok := input["store-id"].(float64)
+	if !ok || storeID <= 0 {
+		handler.rd.JSON(w, http.StatusBadRequest, errors.New("invalid argument for 'store-id'").Error())
+		return
+	}
+	reason, ok := input["reason"].(string)
+	if !ok {
+		handler.rd.JSON(w, http.StatusBadRequest, errors.New("invalid argument for 'reason'").Error())
+		return
+	}
+	evicted, err := handler.config.annotateAndPersist(uint64(storeID), reason)
+	if err != nil {
+		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !evicted {
+		handler.rd.JSON(w, http.StatusBadRequest, errors.Errorf("store %d is not evicted by slow trend", uint64(storeID)).Error())
+		return
+	}
+	log.Info("store evicted by slow trend has been annotated", zap.Uint64("store-id", uint64(storeID)), zap.String("reason", reason))
+	handler.rd.JSON(w, http.StatusOK, "The evicted store is annotated.")
+}
+
+// ListScan lists the slow trends of the stores scanned in the latest tick.
+func (handler *evictSlowTrendHandler) ListScan(w http.ResponseWriter, _ *http.Request) {
+	handler.rd.JSON(w, http.StatusOK, handler.config.getLastScan())
+}
+
+// ListHistory lists the recent evictions with the slow trends at the moments
+// of capturing, evicting and recovering.
+func (handler *evictSlowTrendHandler) ListHistory(w http.ResponseWriter, _ *http.Request) {
+	handler.rd.JSON(w, http.StatusOK, handler.config.getHistory())
+}
+
+// Pause pauses the scheduler for the given duration, unit: s.
+func (handler *evictSlowTrendHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	var input map[string]any
+	if err := apiutil.ReadJSONRespondError(handler.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	duration, ok := input["duration"].(float64)
+	if !ok || duration <= 0 {
+		handler.rd.JSON(w, http.StatusBadRequest, errors.New("invalid argument for 'duration'").Error())
+		return
+	}
+	if err := handler.config.pause(time.Duration(duration) * time.Second); err != nil {
+		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	handler.rd.JSON(w, http.StatusOK, "The scheduler is paused.")
+}
+
+// Resume resumes the paused scheduler.
+func (handler *evictSlowTrendHandler) Resume(w http.ResponseWriter, _ *http.Request) {
+	if err := handler.config.resume(); err != nil {
+		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	handler.rd.JSON(w, http.StatusOK, "The scheduler is resumed.")
+}
+
+func (handler *evictSlowTrendHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	httpCode, v := handler.config.update(data)
+	handler.rd.JSON(w, httpCode, v)
+}
+
+func (handler *evictSlowTrendHandler) ListConfig(w http.ResponseWriter, _ *http.Request) {
+	conf := handler.config.EffectiveConfig()
+	handler.rd.JSON(w, http.StatusOK, conf)
+}
+
+// CandidateSelector selects the slow store to be captured as the candidate of
+// the evict-slow-trend scheduler.
+type CandidateSelector interface {
+	// SelectCandidate returns the store to be captured, nil means no store
+	// should be captured. The stores are fetched from the cluster once per
+	// tick and shared by the scheduler and the selector.
+	SelectCandidate(cluster sche.SchedulerCluster, stores []*core.StoreInfo) *core.StoreInfo
+}
+
+// trendCandidateSelector selects the candidate by the slow trend of stores,
+// it's the default selector.
+type trendCandidateSelector struct {
+	conf *evictSlowTrendSchedulerConfig
+}
+
+// SelectCandidate implements CandidateSelector.
+func (sel *trendCandidateSelector) SelectCandidate(cluster sche.SchedulerCluster, stores []*core.StoreInfo) *core.StoreInfo {
+	return chooseEvictCandidate(cluster, stores, sel.conf, sel.conf.lastCapturedCandidate())
+}
+
+// restoreAwareCluster is implemented by the clusters which know whether a
+// snapshot restore is in progress. The stores may look slow while the data is
+// being restored, so no candidate is captured until the restore is finished.
+type restoreAwareCluster interface {
+	IsSnapshotRecovering() bool
+}
+
+// isRestoring returns whether a snapshot restore is in progress in the cluster.
+func isRestoring(cluster sche.SchedulerCluster) bool {
+	c, ok := cluster.(restoreAwareCluster)
+	return ok && c.IsSnapshotRecovering()
+}
+
+// EvictionTracer traces the lifecycle of the evictions made by the
+// evict-slow-trend scheduler, from capturing the candidate to recovering the
+// evicted store.
+type EvictionTracer interface {
+	// StartSpan starts a span for the eviction lifecycle of the given store.
+	StartSpan(name string, storeID uint64) EvictionSpan
+}
+
+// EvictionSpan is the span of an eviction lifecycle.
+type EvictionSpan interface {
+	// AddEvent records a transition of the eviction lifecycle.
+	AddEvent(name string)
+	// End ends the span.
+	End()
+}
+
+type noopEvictionTracer struct{}
+
+// StartSpan implements EvictionTracer.
+func (noopEvictionTracer) StartSpan(string, uint64) EvictionSpan {
+	return noopEvictionSpan{}
+}
+
+type noopEvictionSpan struct{}
+
+// AddEvent implements EvictionSpan.
+func (noopEvictionSpan) AddEvent(string) {}
+
+// End implements EvictionSpan.
+func (noopEvictionSpan) End() {}
+
+// evictionLifecycle holds the span of the ongoing eviction lifecycle.
+type evictionLifecycle struct {
+	syncutil.Mutex
+	tracer   EvictionTracer
+	notifier *webhookNotifier
+	span     EvictionSpan
+	storeID  uint64
+}
+
+func (l *evictionLifecycle) start(storeID uint64) {
+	l.Lock()
+	defer l.Unlock()
+	if l.span != nil {
+		l.span.End()
+	}
+	l.span, l.storeID = l.tracer.StartSpan(EvictSlowTrendName, storeID), storeID
+	l.span.AddEvent("captured")
+	l.notifier.notify(storeID, "captured")
+}
+
+func (l *evictionLifecycle) event(name string) {
+	l.Lock()
+	defer l.Unlock()
+	if l.span != nil {
+		l.span.AddEvent(name)
+		l.notifier.notify(l.storeID, name)
+	}
+}
+
+func (l *evictionLifecycle) end(name string) {
+	l.Lock()
+	defer l.Unlock()
+	if l.span == nil {
+		return
+	}
+	l.span.AddEvent(name)
+	l.span.End()
+	l.notifier.notify(l.storeID, name)
+	l.span, l.storeID = nil, 0
+}
+
+const (
+	// webhookQueueSize is the maximum number of the events waiting to be posted
+	// to the webhook, the new events are dropped if the queue is full.
+	webhookQueueSize = 64
+	webhookTimeout   = 5 * time.Second
+)
+
+// SlowTrendEvent is the payload posted to the webhook on each transition of the
+// eviction lifecycle.
+type SlowTrendEvent struct {
+	Scheduler string    `json:"scheduler"`
+	StoreID   uint64    `json:"store-id"`
+	Event     string    `json:"event"`
+	TS        time.Time `json:"ts"`
+}
+
+// webhookNotifier posts the events to the webhook by a background worker, so
+// that a slow webhook can't stall the scheduler.
+type webhookNotifier struct {
+	conf      *evictSlowTrendSchedulerConfig
+	client    *http.Client
+	events    chan SlowTrendEvent
+	startOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newWebhookNotifier(conf *evictSlowTrendSchedulerConfig) *webhookNotifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookNotifier{
+		conf:   conf,
+		client: &http.Client{Timeout: webhookTimeout},
+		events: make(chan SlowTrendEvent, webhookQueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (n *webhookNotifier) notify(storeID uint64, event string) {
+	if n.conf.webhookURL() == "" {
+		return
+	}
+	n.startOnce.Do(func() {
+		go n.run()
+	})
+	select {
+	case n.events <- SlowTrendEvent{Scheduler: EvictSlowTrendName, StoreID: storeID, Event: event, TS: n.conf.now()}:
+	default:
+		slowTrendWebhookDroppedCounter.Inc()
+	}
+}
+
+func (n *webhookNotifier) run() {
+	defer logutil.LogPanic()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case event := <-n.events:
+			if err := n.post(event); err != nil {
+				log.Warn("evict-slow-trend-scheduler post event to webhook failed",
+					zap.Uint64("store-id", event.StoreID), zap.String("event", event.Event), errs.ZapError(err))
+				slowTrendWebhookFailedCounter.Inc()
+				continue
+			}
+			slowTrendWebhookSentCounter.Inc()
+		}
+	}
+}
+
+func (n *webhookNotifier) post(event SlowTrendEvent) error {
+	url := n.conf.webhookURL()
+	if url == "" {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *webhookNotifier) stop() {
+	n.cancel()
+}
+
+// EvictSlowTrendError records an error of the evict-slow-trend scheduler.
+type EvictSlowTrendError struct {
+	Err error
+	// TS is the time when the error happened.
+	TS time.Time
+}
+
+type evictSlowTrendScheduler struct {
+	*BaseScheduler
+	conf      *evictSlowTrendSchedulerConfig
+	handler   http.Handler
+	selector  CandidateSelector
+	lifecycle *evictionLifecycle
+
+	errMu   syncutil.RWMutex
+	lastErr *EvictSlowTrendError
+	// The time of the last tick, used to calculate the time spent in each
+	// lifecycle state.
+	lastTickTS time.Time
+
+	traceMu      syncutil.RWMutex
+	traceEnabled bool
+	lastTrace    *SlowTrendTrace
+	// The trace of the ongoing tick, nil if the trace is disabled.
+	trace *SlowTrendTrace
+}
+
+// State returns the lifecycle state of the scheduler, it's one of "idle",
+// "candidate-pending" and "evicting".
+func (s *evictSlowTrendScheduler) State() string {
+	return s.conf.stateName()
+}
+
+// GetStatus implements StatusContributor.
+func (s *evictSlowTrendScheduler) GetStatus() any {
+	cfg := s.conf.EffectiveConfig()
+	return &SlowTrendStatus{
+		State:                 s.conf.stateName(),
+		EvictedStores:         cfg.EvictedStores,
+		EvictedReasons:        cfg.EvictedReasons,
+		Candidate:             cfg.EvictCandidate.StoreID,
+		DetectionMode:         cfg.DetectionMode,
+		ComparisonMethod:      cfg.ComparisonMethod,
+		RecoveryDurationGap:   cfg.RecoveryDurationGap,
+		ConfirmationSamples:   cfg.ConfirmationSamples,
+		MaxEvictionsPerWindow: cfg.MaxEvictionsPerWindow,
+	}
+}
+
+// StoreStatus returns the eviction status of the store.
+func (s *evictSlowTrendScheduler) StoreStatus(storeID uint64) SlowTrendStoreStatus {
+	s.conf.RLock()
+	defer s.conf.RUnlock()
+	return s.conf.storeStatusLocked(storeID)
+}
+
+// BatchStatus returns the eviction status of each of the stores, which are
+// taken from the same snapshot of the scheduler state, e.g., for a dashboard
+// of the whole fleet.
+func (s *evictSlowTrendScheduler) BatchStatus(storeIDs []uint64) map[uint64]SlowTrendStoreStatus {
+	s.conf.RLock()
+	defer s.conf.RUnlock()
+	statuses := make(map[uint64]SlowTrendStoreStatus, len(storeIDs))
+	for _, storeID := range storeIDs {
+		statuses[storeID] = s.conf.storeStatusLocked(storeID)
+	}
+	return statuses
+}
+
+func (conf *evictSlowTrendSchedulerConfig) storeStatusLocked(storeID uint64) SlowTrendStoreStatus {
+	if storeID == 0 {
+		return SlowTrendStoreStatus{State: slowTrendStoreStatusIdle}
 	}
-	recoveryDurationGapFloat, ok := input["recovery-duration"].(float64)
-	if !ok {
-		handler.rd.JSON(w, http.StatusInternalServerError, errors.New("invalid argument for 'recovery-duration'").Error())
-		return
+	for _, evictedID := range conf.EvictedStores {
+		if evictedID == storeID {
+			return SlowTrendStoreStatus{
+				State:            slowTrendStoreStatusEvicted,
+				ElapsedSecs:      conf.secsSince(conf.EvictedTS),
+				RecoveryProgress: conf.recoveryProgressLocked(),
+			}
+		}
 	}
-	handler.config.Lock()
-	defer handler.config.Unlock()
-	prevRecoveryDurationGap := handler.config.RecoveryDurationGap
-	recoveryDurationGap := uint64(recoveryDurationGapFloat)
-	handler.config.RecoveryDurationGap = recoveryDurationGap
-	if err := handler.config.persistLocked(); err != nil {
-		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
-		handler.config.RecoveryDurationGap = prevRecoveryDurationGap
-		return
+	if conf.EvictCandidate.StoreID == storeID {
+		return SlowTrendStoreStatus{
+			State:       slowTrendStoreStatusCandidate,
+			ElapsedSecs: conf.secsSince(conf.EvictCandidate.CaptureTS),
+		}
 	}
-	log.Info("evict-slow-trend-scheduler update 'recovery-duration' - unit: s", zap.Uint64("prev", prevRecoveryDurationGap), zap.Uint64("cur", recoveryDurationGap))
-	handler.rd.JSON(w, http.StatusOK, "Config updated.")
+	return SlowTrendStoreStatus{State: slowTrendStoreStatusIdle}
 }
 
-func (handler *evictSlowTrendHandler) ListConfig(w http.ResponseWriter, _ *http.Request) {
-	conf := handler.config.Clone()
-	handler.rd.JSON(w, http.StatusOK, conf)
+// LastError returns the last error of the scheduler, such as failing to persist
+// the config, nil means no error happened. It's used to detect the persistent
+// failures since `Schedule` does not return errors.
+func (s *evictSlowTrendScheduler) LastError() *EvictSlowTrendError {
+	s.errMu.RLock()
+	defer s.errMu.RUnlock()
+	if s.lastErr == nil {
+		return nil
+	}
+	lastErr := *s.lastErr
+	return &lastErr
 }
 
-type evictSlowTrendScheduler struct {
-	*BaseScheduler
-	conf    *evictSlowTrendSchedulerConfig
-	handler http.Handler
+// observeStateDuration adds the time elapsed since the last tick to the current
+// lifecycle state, which is the state kept since the last tick.
+func (s *evictSlowTrendScheduler) observeStateDuration() {
+	now := s.conf.now()
+	if !s.lastTickTS.IsZero() && now.After(s.lastTickTS) {
+		storeSlowTrendStateDurationCounter.WithLabelValues(s.State()).Add(now.Sub(s.lastTickTS).Seconds())
+	}
+	s.lastTickTS = now
+}
+
+func (s *evictSlowTrendScheduler) recordError(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	s.lastErr = &EvictSlowTrendError{Err: err, TS: s.conf.now()}
 }
 
 func (s *evictSlowTrendScheduler) GetNextInterval(time.Duration) time.Duration {
@@ -287,6 +2820,17 @@ func (s *evictSlowTrendScheduler) GetNextInterval(time.Duration) time.Duration {
 	return intervalGrow(s.GetMinInterval(), MaxScheduleInterval, growthType)
 }
 
+// Pause pauses the scheduler for the given duration. The scheduler keeps the
+// existing eviction but does not change anything while it's paused.
+func (s *evictSlowTrendScheduler) Pause(duration time.Duration) error {
+	return s.conf.pause(duration)
+}
+
+// Resume resumes the paused scheduler.
+func (s *evictSlowTrendScheduler) Resume() error {
+	return s.conf.resume()
+}
+
 func (s *evictSlowTrendScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.handler.ServeHTTP(w, r)
 }
@@ -313,10 +2857,14 @@ func (s *evictSlowTrendScheduler) ReloadConfig() error {
 	if len(cfgData) == 0 {
 		return nil
 	}
-	newCfg := &evictSlowTrendSchedulerConfig{}
+	// Use the default values for the items missing in the persisted config.
+	newCfg := initEvictSlowTrendSchedulerConfig(nil)
 	if err = DecodeConfig([]byte(cfgData), newCfg); err != nil {
 		return err
 	}
+	if err := newCfg.checkContradictionLocked(); err != nil {
+		log.Warn("evict-slow-trend-scheduler does not capture slow stores until the contradictory config is fixed", errs.ZapError(err))
+	}
 	old := make(map[uint64]struct{})
 	for _, id := range s.conf.EvictedStores {
 		old[id] = struct{}{}
@@ -327,40 +2875,301 @@ func (s *evictSlowTrendScheduler) ReloadConfig() error {
 	}
 	pauseAndResumeLeaderTransfer(s.conf.cluster, old, new)
 	s.conf.RecoveryDurationGap = newCfg.RecoveryDurationGap
+	s.conf.RecoveryGapScalingFactor = newCfg.RecoveryGapScalingFactor
+	s.conf.MaxRecoveryDurationGap = newCfg.MaxRecoveryDurationGap
+	s.conf.UseSlowScore = newCfg.UseSlowScore
+	s.conf.ResultFieldsOptional = newCfg.ResultFieldsOptional
+	s.conf.ClusterSlowCauseValueCeiling = newCfg.ClusterSlowCauseValueCeiling
+	s.conf.RebalanceOnRecover = newCfg.RebalanceOnRecover
+	s.conf.MaxEvictionsPerWindow = newCfg.MaxEvictionsPerWindow
+	s.conf.EvictionBudgetWindow = newCfg.EvictionBudgetWindow
+	s.conf.EvictOnWriteStall = newCfg.EvictOnWriteStall
+	s.conf.WriteStallDuration = newCfg.WriteStallDuration
+	s.conf.ConfirmationSamples = newCfg.ConfirmationSamples
+	s.conf.ConfirmationInterval = newCfg.ConfirmationInterval
+	s.conf.ScatterOnEvict = newCfg.ScatterOnEvict
+	s.conf.LogLevel = newCfg.LogLevel
+	s.conf.DetectionMode = newCfg.DetectionMode
+	s.conf.BaselineRegressionRatio = newCfg.BaselineRegressionRatio
+	s.conf.ConsiderPreparingStores = newCfg.ConsiderPreparingStores
+	s.conf.MinImpactScore = newCfg.MinImpactScore
+	s.conf.RecoveryStabilityWindow = newCfg.RecoveryStabilityWindow
+	s.conf.MinUsedSpaceRatio = newCfg.MinUsedSpaceRatio
+	s.conf.EvictOperatorTimeout = newCfg.EvictOperatorTimeout
+	s.conf.WarmupDuration = newCfg.WarmupDuration
+	s.conf.MinClusterLeaderCount = newCfg.MinClusterLeaderCount
+	s.conf.SkipRecoveredOnRemoval = newCfg.SkipRecoveredOnRemoval
+	s.conf.ConfidenceThreshold = newCfg.ConfidenceThreshold
+	s.conf.ConfidenceDecay = newCfg.ConfidenceDecay
+	s.conf.PickWorstCandidate = newCfg.PickWorstCandidate
+	s.conf.TailLatencyThreshold = newCfg.TailLatencyThreshold
+	s.conf.MaintenanceWindowStart = newCfg.MaintenanceWindowStart
+	s.conf.MaintenanceWindowEnd = newCfg.MaintenanceWindowEnd
+	s.conf.MaintenanceWindowTimezone = newCfg.MaintenanceWindowTimezone
+	s.conf.ComparisonMethod = newCfg.ComparisonMethod
+	s.conf.CriticalKeyRanges = newCfg.CriticalKeyRanges
+	s.conf.CriticalRegionPolicy = newCfg.CriticalRegionPolicy
+	s.conf.EngineFilter = newCfg.EngineFilter
+	s.conf.ComparisonEpsilon = newCfg.ComparisonEpsilon
+	s.conf.WebhookURL = newCfg.WebhookURL
+	s.conf.MinResultRateDrop = newCfg.MinResultRateDrop
+	s.conf.KeyRanges = newCfg.KeyRanges
+	s.conf.PersistFailurePolicy = newCfg.PersistFailurePolicy
+	s.conf.EnableDiskSlowEvict = newCfg.EnableDiskSlowEvict
+	s.conf.EnableNetworkSlowEvict = newCfg.EnableNetworkSlowEvict
+	s.conf.FailureDomainLabel = newCfg.FailureDomainLabel
+	s.conf.FlapThreshold = newCfg.FlapThreshold
+	s.conf.FlapWindow = newCfg.FlapWindow
+	s.conf.FlapHoldDuration = newCfg.FlapHoldDuration
+	s.conf.SoftEvict = newCfg.SoftEvict
+	s.conf.EvictOperatorLimit = newCfg.EvictOperatorLimit
+	s.conf.EvictRampInitialBatch = newCfg.EvictRampInitialBatch
+	s.conf.EvictRampStep = newCfg.EvictRampStep
+	s.conf.EvictRampInterval = newCfg.EvictRampInterval
+	s.conf.PerStoreRecoveryGap = newCfg.PerStoreRecoveryGap
+	s.conf.EvictionSLA = newCfg.EvictionSLA
+	s.conf.QuorumDenominator = newCfg.QuorumDenominator
+	s.conf.RegionAwareAffectedCount = newCfg.RegionAwareAffectedCount
+	s.conf.CandidateCancelCooldown = newCfg.CandidateCancelCooldown
+	s.conf.MinTrendDataAge = newCfg.MinTrendDataAge
+	s.conf.AutoDisableAfterIdle = newCfg.AutoDisableAfterIdle
+	s.conf.ReferenceGroupLabel = newCfg.ReferenceGroupLabel
+	s.conf.EvictSettlePeriod = newCfg.EvictSettlePeriod
+	s.conf.RecoveryFastScans = newCfg.RecoveryFastScans
+	s.conf.RecoveryQuorumRatio = newCfg.RecoveryQuorumRatio
+	s.conf.SymmetricRecovery = newCfg.SymmetricRecovery
+	s.conf.OfflineEvictedStorePolicy = newCfg.OfflineEvictedStorePolicy
+	s.conf.AffectedRatioOverEligible = newCfg.AffectedRatioOverEligible
+	s.conf.ReadmissionStages = newCfg.ReadmissionStages
+	s.conf.ReadmissionStageDuration = newCfg.ReadmissionStageDuration
+	s.conf.RequireBecomeFastEvent = newCfg.RequireBecomeFastEvent
+	s.conf.AffectedStoreRatioThreshold = newCfg.AffectedStoreRatioThreshold
+	s.conf.Profile = newCfg.Profile
+	s.conf.PriorityKeyRanges = newCfg.PriorityKeyRanges
+	s.conf.MinStoresForEvicting = newCfg.MinStoresForEvicting
+	s.conf.RecentEvictions = newCfg.RecentEvictions
+	s.conf.EvictCandidate = newCfg.EvictCandidate
+	s.conf.LastEvictCandidate = newCfg.LastEvictCandidate
 	s.conf.EvictedStores = newCfg.EvictedStores
+	s.conf.EvictedReasons = newCfg.EvictedReasons
+	s.conf.EvictedTS = newCfg.EvictedTS
+	s.conf.PausedUntil = newCfg.PausedUntil
+	s.conf.Confidences = newCfg.Confidences
+	s.conf.EvictionCounts = newCfg.EvictionCounts
+	s.conf.LastActiveTS = newCfg.LastActiveTS
+	s.conf.SoftEvictedWeight = newCfg.SoftEvictedWeight
+	s.conf.Readmission = newCfg.Readmission
 	return nil
 }
 
 func (s *evictSlowTrendScheduler) PrepareConfig(cluster sche.SchedulerCluster) error {
-	evictedStoreID := s.conf.evictedStore()
-	if evictedStoreID == 0 {
-		return nil
+	cluster.GetBasicCluster().RegisterStoreRemovedListener(s.GetName(), s.onStoreRemoved)
+	if err := s.conf.contradiction(); err != nil {
+		log.Warn("evict-slow-trend-scheduler does not capture slow stores until the contradictory config is fixed", errs.ZapError(err))
+	}
+	// Re-apply the eviction of all persisted stores, and do not stop at the
+	// first failure so that the other stores are still evicted.
+	var firstErr error
+	for _, storeID := range s.conf.getStores() {
+		if s.conf.isSoftEvicted(storeID) {
+			continue
+		}
+		if err := cluster.SlowTrendEvicted(storeID); err != nil && !errs.ErrSlowTrendEvicted.Equal(err) {
+			log.Warn("evict-slow-trend-scheduler re-apply eviction failed", zap.Uint64("store-id", storeID), errs.ZapError(err))
+			s.recordError(err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	s.reconcileInflightOperators(cluster)
+	return firstErr
+}
+
+// reconcileInflightOperators records the regions led by the evicted stores
+// which already have the operators, e.g., the eviction operators issued by the
+// previous leader before the failover, so that they are not issued again.
+func (s *evictSlowTrendScheduler) reconcileInflightOperators(cluster sche.SchedulerCluster) {
+	evicted := make(map[uint64]struct{})
+	for _, storeID := range s.conf.getStores() {
+		evicted[storeID] = struct{}{}
+	}
+	regions := make(map[uint64]struct{})
+	if len(evicted) > 0 && s.OpController != nil {
+		for _, op := range s.OpController.GetOperators() {
+			region := cluster.GetRegion(op.RegionID())
+			if region == nil {
+				continue
+			}
+			if _, ok := evicted[region.GetLeader().GetStoreId()]; ok {
+				regions[op.RegionID()] = struct{}{}
+			}
+		}
 	}
-	return cluster.SlowTrendEvicted(evictedStoreID)
+	if len(regions) > 0 {
+		log.Info("evict-slow-trend-scheduler reconciled the in-flight operators", zap.Int("region-count", len(regions)))
+		storeSlowTrendActionStatusGauge.WithLabelValues("evict", "reconciled_inflight").Add(float64(len(regions)))
+	}
+	s.conf.setInflightRegions(regions, func(regionID uint64) bool {
+		return s.OpController.GetOperator(regionID) != nil
+	})
 }
 
 func (s *evictSlowTrendScheduler) CleanConfig(cluster sche.SchedulerCluster) {
-	s.cleanupEvictLeader(cluster)
+	cluster.GetBasicCluster().UnregisterStoreRemovedListener(s.GetName())
+	s.cleanupEvictLeader(cluster, evictCleanupReasonCleaned)
+	s.finishReadmission(cluster)
+	s.lifecycle.notifier.stop()
+}
+
+// onStoreRemoved cleans up the states of the removed store immediately, rather
+// than waiting for the next schedule.
+func (s *evictSlowTrendScheduler) onStoreRemoved(store *core.StoreInfo) {
+	storeID := store.GetID()
+	if s.conf.candidate() == storeID {
+		s.conf.popCandidate(false)
+		log.Info("slow store candidate by trend has been removed", zap.Uint64("store-id", storeID))
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "canceled_removed").Inc()
+		slowTrendCandidateCanceledCounter.Inc()
+		slowTrendCandidateExitRemovedCounter.Inc()
+		s.lifecycle.end("canceled")
+	}
+	if s.conf.evictedStore() != storeID {
+		return
+	}
+	log.Info("store evicted by slow trend has been removed", zap.Uint64("store-id", storeID),
+		zap.String("reason", s.conf.evictedReason(storeID)))
+	storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_removed").Inc()
+	storeSlowTrendEvictedStatusGauge.WithLabelValues(store.GetAddress(), strconv.FormatUint(storeID, 10)).Set(0)
+	clearRecoveryProgress(storeID)
+	if err := s.conf.clearStoresAndPersist(); err != nil {
+		log.Info("evict-slow-trend-scheduler persist config failed", zap.Uint64("store-id", storeID))
+		s.recordError(err)
+	}
+	s.conf.markCandidateRecovered()
+	s.lifecycle.end("removed")
+}
+
+// ClearEvictedStore clears the eviction of the given store manually, e.g., it
+// has been confirmed to be healthy externally, and keeps the other evicted
+// stores as they are. If the clearance fails to be persisted, it's rolled
+// back or kept according to `PersistFailurePolicy`.
+func (s *evictSlowTrendScheduler) ClearEvictedStore(cluster sche.SchedulerCluster, storeID uint64) error {
+	reason := s.conf.evictedReason(storeID)
+	removed, err := s.conf.removeStoreAndPersist(storeID)
+	if !removed {
+		return errors.Errorf("store %d is not evicted by slow trend", storeID)
+	}
+	if err != nil {
+		log.Info("evict-slow-trend-scheduler persist config failed", zap.Uint64("store-id", storeID))
+		s.recordError(err)
+		if s.conf.isEvicted(storeID) {
+			// The clearance has been rolled back, keep evicting the store.
+			return err
+		}
+	}
+	log.Info("store evicted by slow trend has been cleared manually", zap.Uint64("store-id", storeID), zap.String("reason", reason))
+	storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_manual").Inc()
+	address := "?"
+	if store := cluster.GetStore(storeID); store != nil {
+		address = store.GetAddress()
+	}
+	storeSlowTrendEvictedStatusGauge.WithLabelValues(address, strconv.FormatUint(storeID, 10)).Set(0)
+	clearRecoveryProgress(storeID)
+	if s.conf.lastCapturedCandidate().StoreID == storeID {
+		s.conf.markCandidateRecovered()
+	}
+	if !s.conf.hasEvictedStores() {
+		s.lifecycle.end("cleared")
+	}
+	if !s.conf.isSoftEvicted(storeID) {
+		cluster.SlowTrendRecovered(storeID)
+	}
+	s.restoreLeaderWeight(cluster, storeID)
+	return nil
+}
+
+// autoDisableIfIdle removes the scheduler itself if no capture or eviction has
+// happened for `AutoDisableAfterIdle`.
+func (s *evictSlowTrendScheduler) autoDisableIfIdle() {
+	idle, duration := s.conf.idleTooLong()
+	if !idle || s.conf.removeSchedulerCb == nil {
+		return
+	}
+	log.Info("evict-slow-trend-scheduler has been idle for too long, remove it",
+		zap.Duration("idle", duration),
+		zap.Uint64("auto-disable-after-idle", s.conf.Clone().AutoDisableAfterIdle))
+	if err := s.conf.removeSchedulerCb(s.GetName()); err != nil {
+		log.Warn("evict-slow-trend-scheduler failed to remove itself", errs.ZapError(err))
+		s.recordError(err)
+		return
+	}
+	storeSlowTrendActionStatusGauge.WithLabelValues("scheduler", "auto_disabled").Inc()
+	s.trace.branch("auto_disabled")
+	s.conf.Lock()
+	s.conf.autoDisabled = true
+	s.conf.Unlock()
+}
+
+// VerifyState cross-checks the persisted evicted stores against the stores
+// marked as evicted by slow trend in the cluster, and reports the discrepancy.
+// If repair is true, the cluster is repaired to match the persisted state.
+func (s *evictSlowTrendScheduler) VerifyState(cluster sche.SchedulerCluster, repair bool) *SlowTrendStateDrift {
+	return s.conf.verifyState(cluster, repair)
 }
 
 func (s *evictSlowTrendScheduler) prepareEvictLeader(cluster sche.SchedulerCluster, storeID uint64) error {
+	// The store is evicted again before it's fully re-admitted, restore its
+	// leader weight first so that the original one is not lost.
+	if s.conf.readmission().StoreID == storeID {
+		s.finishReadmission(cluster)
+	}
 	err := s.conf.setStoreAndPersist(storeID)
 	if err != nil {
 		log.Info("evict-slow-trend-scheduler persist config failed", zap.Uint64("store-id", storeID))
+		s.recordError(err)
+		return err
+	}
+	if s.conf.isFlapping(storeID) {
+		s.conf.getLogger().Warn("store is flapping between evicted and recovered by slow trend, hold it evicted",
+			zap.Uint64("store-id", storeID),
+			zap.Uint64("hold-duration", s.conf.Clone().FlapHoldDuration))
+		evictSlowTrendFlappingCounter.Inc()
+	}
+	// The soft evicted store is not marked as evicted, so that it can still take
+	// leaders, while balance-leader prefers the others for its lower weight.
+	if store := cluster.GetStore(storeID); s.conf.isSoftEvict() && store != nil && s.softEvictLeader(cluster, store) {
+		return nil
+	}
+	if err := cluster.SlowTrendEvicted(storeID); err != nil {
+		s.recordError(err)
 		return err
 	}
-	return cluster.SlowTrendEvicted(storeID)
+	return nil
 }
 
-func (s *evictSlowTrendScheduler) cleanupEvictLeader(cluster sche.SchedulerCluster) {
+func (s *evictSlowTrendScheduler) cleanupEvictLeader(cluster sche.SchedulerCluster, reason evictCleanupReason) {
 	evictedStoreID, err := s.conf.clearAndPersist(cluster)
 	if err != nil {
 		log.Info("evict-slow-trend-scheduler persist config failed", zap.Uint64("store-id", evictedStoreID))
+		s.recordError(err)
+		if s.conf.evictedStore() == evictedStoreID {
+			// The cleanup has been rolled back, keep evicting the store.
+			return
+		}
 	}
 	if evictedStoreID != 0 {
-		// Assertion: evictStoreID == s.conf.LastEvictCandidate.storeID
+		// Assertion: evictStoreID == s.conf.LastEvictCandidate.StoreID
 		s.conf.markCandidateRecovered()
-		cluster.SlowTrendRecovered(evictedStoreID)
+		// The soft evicted store is not marked as evicted in the cluster.
+		if !s.conf.isSoftEvicted(evictedStoreID) && (reason != evictCleanupReasonRemoved || !s.conf.Clone().SkipRecoveredOnRemoval) {
+			cluster.SlowTrendRecovered(evictedStoreID)
+		}
+		s.restoreLeaderWeight(cluster, evictedStoreID)
+		if reason == evictCleanupReasonRecovered {
+			s.startReadmission(cluster, evictedStoreID)
+		}
+		s.conf.recordRecoveryHistory(cluster.GetStore(evictedStoreID), evictedStoreID, reason)
 	}
 }
 
@@ -370,14 +3179,402 @@ func (s *evictSlowTrendScheduler) scheduleEvictLeader(cluster sche.SchedulerClus
 		return nil
 	}
 	storeSlowTrendEvictedStatusGauge.WithLabelValues(store.GetAddress(), strconv.FormatUint(store.GetID(), 10)).Set(1)
-	return scheduleEvictLeaderBatch(s.GetName(), s.GetType(), cluster, s.conf, EvictLeaderBatchSize)
+	if s.conf.isSoftEvicted(store.GetID()) {
+		if s.conf.isSoftEvict() && s.softEvictLeader(cluster, store) {
+			return nil
+		}
+		// The soft eviction is turned off or not supported anymore, evict the
+		// leaders fully instead.
+		s.restoreLeaderWeight(cluster, store.GetID())
+		if err := cluster.SlowTrendEvicted(store.GetID()); err != nil && !errs.ErrSlowTrendEvicted.Equal(err) {
+			s.recordError(err)
+			return nil
+		}
+	}
+	ops := scheduleEvictLeaderBatch(s.GetName(), s.GetType(), cluster, s.conf, s.conf.evictBatchSize())
+	storeSlowTrendLeadersMovedCounter.WithLabelValues(strconv.FormatUint(store.GetID(), 10)).Add(float64(len(ops)))
+	return ops
+}
+
+// storeWeightSetter is implemented by the clusters which persist the store
+// weights, e.g., the cluster of the PD server. The scheduling service can't
+// change the weights, since its stores are synced from the PD server.
+type storeWeightSetter interface {
+	SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) error
+}
+
+// getStoreWeightSetter returns the setter of the store weights, nil means the
+// weights can't be persisted by the cluster.
+func getStoreWeightSetter(cluster sche.SchedulerCluster) storeWeightSetter {
+	if c, ok := cluster.(*cacheCluster); ok {
+		cluster = c.SchedulerCluster
+	}
+	setter, _ := cluster.(storeWeightSetter)
+	return setter
+}
+
+// softEvictLeader lowers the leader weight of the evicted store in proportion to
+// its slowness, so that balance-leader sheds its leaders gradually. It returns
+// false if the weight can't be persisted, and the leaders should be evicted
+// instead.
+func (s *evictSlowTrendScheduler) softEvictLeader(cluster sche.SchedulerCluster, store *core.StoreInfo) bool {
+	setter := getStoreWeightSetter(cluster)
+	if setter == nil {
+		storeSlowTrendActionStatusGauge.WithLabelValues("evict", "soft_unsupported").Inc()
+		return false
+	}
+	change := s.conf.softEvictedWeight()
+	// If the weight has been changed by others since it was lowered, the
+	// current one is regarded as the original one.
+	if change.StoreID != store.GetID() || change.Weight != store.GetLeaderWeight() {
+		change = leaderWeightChange{StoreID: store.GetID(), OriginalWeight: store.GetLeaderWeight()}
+	}
+	weight := change.OriginalWeight * calcSoftEvictLeaderWeightRatio(cluster.GetStores(), store)
+	storeSlowTrendMiscGauge.WithLabelValues("evict", "soft_leader_weight").Set(weight)
+	if change.StoreID == store.GetID() && change.Weight == weight {
+		return true
+	}
+	// Persist the original weight before changing it.
+	change.Weight = weight
+	if err := s.conf.setSoftEvictedWeight(change); err != nil {
+		log.Warn("evict-slow-trend-scheduler failed to persist the leader weight of the soft evicted store",
+			zap.Uint64("store-id", store.GetID()), errs.ZapError(err))
+		s.recordError(err)
+		if s.conf.softEvictedWeight() != change {
+			// The change has been rolled back.
+			return s.conf.isSoftEvicted(store.GetID())
+		}
+	}
+	if store.GetLeaderWeight() == weight {
+		return true
+	}
+	log.Info("evict-slow-trend-scheduler lower the leader weight of the soft evicted store",
+		zap.Uint64("store-id", store.GetID()),
+		zap.Float64("original-weight", change.OriginalWeight),
+		zap.Float64("weight", weight))
+	if err := setter.SetStoreWeight(store.GetID(), weight, store.GetRegionWeight()); err != nil {
+		log.Warn("evict-slow-trend-scheduler failed to lower the leader weight of the soft evicted store",
+			zap.Uint64("store-id", store.GetID()), errs.ZapError(err))
+		s.recordError(err)
+	}
+	return true
+}
+
+// restoreLeaderWeight restores the leader weight of the store if it has been
+// soft evicted, unless the weight has been changed by others.
+func (s *evictSlowTrendScheduler) restoreLeaderWeight(cluster sche.SchedulerCluster, storeID uint64) {
+	change := s.conf.softEvictedWeight()
+	if change.StoreID != storeID {
+		return
+	}
+	store, setter := cluster.GetStore(storeID), getStoreWeightSetter(cluster)
+	switch {
+	case store == nil || setter == nil:
+		// Nothing can be restored, e.g., the store has been removed.
+	case store.GetLeaderWeight() != change.Weight:
+		storeSlowTrendActionStatusGauge.WithLabelValues("recover", "skip_restore_weight").Inc()
+		log.Info("evict-slow-trend-scheduler skip restoring the leader weight changed by others",
+			zap.Uint64("store-id", storeID),
+			zap.Float64("weight", store.GetLeaderWeight()),
+			zap.Float64("original-weight", change.OriginalWeight))
+	default:
+		log.Info("evict-slow-trend-scheduler restore the leader weight of the soft evicted store",
+			zap.Uint64("store-id", storeID),
+			zap.Float64("weight", change.OriginalWeight))
+		if err := setter.SetStoreWeight(storeID, change.OriginalWeight, store.GetRegionWeight()); err != nil {
+			log.Warn("evict-slow-trend-scheduler failed to restore the leader weight of the soft evicted store",
+				zap.Uint64("store-id", storeID), errs.ZapError(err))
+			s.recordError(err)
+			return
+		}
+	}
+	if err := s.conf.setSoftEvictedWeight(leaderWeightChange{}); err != nil {
+		s.recordError(err)
+	}
+}
+
+// startReadmission lowers the leader weight of the recovered store to the first
+// stage of the re-admission, and it's ramped up by the following ticks.
+func (s *evictSlowTrendScheduler) startReadmission(cluster sche.SchedulerCluster, storeID uint64) {
+	store := cluster.GetStore(storeID)
+	if store == nil || !s.conf.readmissionEnabled() {
+		return
+	}
+	if getStoreWeightSetter(cluster) == nil {
+		storeSlowTrendActionStatusGauge.WithLabelValues("recover", "readmission_unsupported").Inc()
+		return
+	}
+	// Persist the original weight before changing it.
+	r := storeReadmission{
+		StoreID:        storeID,
+		OriginalWeight: store.GetLeaderWeight(),
+		Weight:         store.GetLeaderWeight(),
+		StartTS:        s.conf.now(),
+	}
+	if err := s.conf.setReadmission(r); err != nil {
+		log.Warn("evict-slow-trend-scheduler failed to persist the re-admission",
+			zap.Uint64("store-id", storeID), errs.ZapError(err))
+		s.recordError(err)
+		return
+	}
+	storeSlowTrendActionStatusGauge.WithLabelValues("recover", "readmission_start").Inc()
+	s.rampReadmission(cluster)
+}
+
+// rampReadmission sets the leader weight of the store being re-admitted by the
+// current stage, and fully restores it after the last stage.
+func (s *evictSlowTrendScheduler) rampReadmission(cluster sche.SchedulerCluster) {
+	r := s.conf.readmission()
+	if r.StoreID == 0 {
+		return
+	}
+	store, setter := cluster.GetStore(r.StoreID), getStoreWeightSetter(cluster)
+	if store == nil || setter == nil || store.GetLeaderWeight() != r.Weight {
+		s.finishReadmission(cluster)
+		return
+	}
+	stage, weight, done := s.conf.readmissionStage(r)
+	if done {
+		s.finishReadmission(cluster)
+		return
+	}
+	storeSlowTrendMiscGauge.WithLabelValues("recover", "readmission_leader_weight").Set(weight)
+	if r.Stage == stage && r.Weight == weight {
+		return
+	}
+	r.Stage, r.Weight = stage, weight
+	if err := s.conf.setReadmission(r); err != nil {
+		log.Warn("evict-slow-trend-scheduler failed to persist the re-admission",
+			zap.Uint64("store-id", r.StoreID), errs.ZapError(err))
+		s.recordError(err)
+		return
+	}
+	log.Info("evict-slow-trend-scheduler ramp up the leader weight of the re-admitted store",
+		zap.Uint64("store-id", r.StoreID),
+		zap.Int("stage", stage),
+		zap.Float64("weight", weight))
+	if err := setter.SetStoreWeight(r.StoreID, weight, store.GetRegionWeight()); err != nil {
+		log.Warn("evict-slow-trend-scheduler failed to ramp up the leader weight of the re-admitted store",
+			zap.Uint64("store-id", r.StoreID), errs.ZapError(err))
+		s.recordError(err)
+	}
+}
+
+// finishReadmission stops re-admitting the store and restores its original
+// leader weight, unless the weight has been changed by others.
+func (s *evictSlowTrendScheduler) finishReadmission(cluster sche.SchedulerCluster) {
+	r := s.conf.readmission()
+	if r.StoreID == 0 {
+		return
+	}
+	storeSlowTrendActionStatusGauge.WithLabelValues("recover", "readmission_done").Inc()
+	store, setter := cluster.GetStore(r.StoreID), getStoreWeightSetter(cluster)
+	switch {
+	case store == nil || setter == nil:
+		// Nothing can be restored, e.g., the store has been removed.
+	case store.GetLeaderWeight() != r.Weight:
+		storeSlowTrendActionStatusGauge.WithLabelValues("recover", "skip_restore_weight").Inc()
+		log.Info("evict-slow-trend-scheduler stop re-admitting the store whose leader weight is changed by others",
+			zap.Uint64("store-id", r.StoreID),
+			zap.Float64("weight", store.GetLeaderWeight()),
+			zap.Float64("original-weight", r.OriginalWeight))
+	default:
+		log.Info("evict-slow-trend-scheduler fully restore the leader weight of the re-admitted store",
+			zap.Uint64("store-id", r.StoreID),
+			zap.Float64("weight", r.OriginalWeight))
+		if err := setter.SetStoreWeight(r.StoreID, r.OriginalWeight, store.GetRegionWeight()); err != nil {
+			log.Warn("evict-slow-trend-scheduler failed to restore the leader weight of the re-admitted store",
+				zap.Uint64("store-id", r.StoreID), errs.ZapError(err))
+			s.recordError(err)
+			return
+		}
+	}
+	if err := s.conf.setReadmission(storeReadmission{}); err != nil {
+		s.recordError(err)
+	}
+}
+
+// calcSoftEvictLeaderWeightRatio calculates the ratio of the leader weight of
+// the soft evicted store to its original one, which is the ratio of the median
+// `CauseValue` of the other stores to the one of the store.
+func calcSoftEvictLeaderWeightRatio(stores []*core.StoreInfo, store *core.StoreInfo) float64 {
+	slowTrend := store.GetSlowTrend()
+	if slowTrend == nil || slowTrend.CauseValue <= 0 {
+		return 1.0
+	}
+	var causeValues []float64
+	for _, other := range stores {
+		if other.GetID() == store.GetID() || other.GetSlowTrend() == nil {
+			continue
+		}
+		causeValues = append(causeValues, other.GetSlowTrend().CauseValue)
+	}
+	if len(causeValues) == 0 {
+		return 1.0
+	}
+	ratio := calcMedian(causeValues) / slowTrend.CauseValue
+	return math.Max(minSoftEvictLeaderWeightRatio, math.Min(1.0, ratio))
+}
+
+// scheduleTransferLeaderBack transfers a part of leaders back to the recovered
+// store, the number of leaders is limited by `EvictLeaderBatchSize` and the gap
+// between the leader count of the store and the average one.
+func (s *evictSlowTrendScheduler) scheduleTransferLeaderBack(cluster sche.SchedulerCluster, storeID uint64) []*operator.Operator {
+	store := cluster.GetStore(storeID)
+	if store == nil {
+		return nil
+	}
+	var totalLeaderCount, storeCount int
+	for _, st := range cluster.GetStores() {
+		if !(st.IsPreparing() || st.IsServing()) {
+			continue
+		}
+		totalLeaderCount += st.GetLeaderCount()
+		storeCount++
+	}
+	if storeCount == 0 {
+		return nil
+	}
+	batchSize := totalLeaderCount/storeCount - store.GetLeaderCount()
+	if batchSize <= 0 {
+		return nil
+	}
+	if batchSize > EvictLeaderBatchSize {
+		batchSize = EvictLeaderBatchSize
+	}
+	filters := []filter.Filter{&filter.StoreStateFilter{ActionScope: s.GetName(), TransferLeader: true, OperatorLevel: constant.Medium}}
+	if !filter.Target(cluster.GetSchedulerConfig(), store, filters) {
+		return nil
+	}
+	pendingFilter := filter.NewRegionPendingFilter()
+	downFilter := filter.NewRegionDownFilter()
+	var ops []*operator.Operator
+	for _, region := range cluster.RandFollowerRegions(storeID, []core.KeyRange{core.NewKeyRange("", "")}) {
+		if len(ops) >= batchSize {
+			break
+		}
+		if filter.SelectOneRegion([]*core.RegionInfo{region}, nil, pendingFilter, downFilter) == nil {
+			continue
+		}
+		op, err := operator.CreateTransferLeaderOperator(s.GetType(), cluster, region, storeID, []uint64{}, operator.OpLeader)
+		if err != nil {
+			log.Debug("fail to create transfer leader back operator", errs.ZapError(err))
+			continue
+		}
+		ops = uniqueAppendOperator(ops, op)
+	}
+	if len(ops) > 0 {
+		log.Info("evict-slow-trend-scheduler transfer leaders back to the recovered store",
+			zap.Uint64("store-id", storeID), zap.Int("operator-count", len(ops)))
+	}
+	return ops
+}
+
+// updateRecoveryProgress reports how close the evicted store is to recovery,
+// whether it's faster than the others now, and whether it has been evicted for
+// longer than the SLA.
+func (s *evictSlowTrendScheduler) updateRecoveryProgress(stores []*core.StoreInfo, storeID uint64, store *core.StoreInfo) {
+	if store == nil || store.IsRemoved() {
+		return
+	}
+	label := strconv.FormatUint(storeID, 10)
+	storeSlowTrendRecoveryProgressGauge.WithLabelValues(label, "progress").Set(s.conf.recoveryProgress())
+	faster := 0.0
+	if s.isCandidateRecovered(stores, store) {
+		faster = 1.0
+	}
+	storeSlowTrendRecoveryProgressGauge.WithLabelValues(label, "faster").Set(faster)
+	breach := 0.0
+	if s.conf.slaBreached() {
+		breach = 1.0
+	}
+	storeSlowTrendEvictionSLABreach.WithLabelValues(label).Set(breach)
+}
+
+// clearRecoveryProgress drops the recovery progress of the store which is not
+// evicted anymore.
+func clearRecoveryProgress(storeID uint64) {
+	label := strconv.FormatUint(storeID, 10)
+	storeSlowTrendRecoveryProgressGauge.DeleteLabelValues(label, "progress")
+	storeSlowTrendRecoveryProgressGauge.DeleteLabelValues(label, "faster")
+	storeSlowTrendEvictionSLABreach.WithLabelValues(label).Set(0)
+}
+
+// slaBreached returns true if the last captured candidate has been evicted for
+// longer than the SLA.
+func (conf *evictSlowTrendSchedulerConfig) slaBreached() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.EvictionSLA == 0 || conf.LastEvictCandidate.CaptureTS.IsZero() {
+		return false
+	}
+	return conf.lastCandidateCapturedSecs() > conf.EvictionSLA
+}
+
+// isEvictedStoreRecovered checks whether the evicted store can be recovered
+// under the detection mode.
+func (s *evictSlowTrendScheduler) isEvictedStoreRecovered(stores []*core.StoreInfo, store *core.StoreInfo) bool {
+	// Do not recover the store based on the immature slow trend, e.g., it just
+	// restarted.
+	if !s.conf.trendMature(store.GetID()) {
+		return false
+	}
+	if s.conf.isLongitudinal() {
+		return checkStoreDataRefreshed(store, s.conf.evictedTS()) && !s.conf.regressedAgainstBaseline(store)
+	}
+	cfg := s.conf.Clone()
+	stores = filterReferenceGroup(s.conf.quorumStores(stores), store, cfg.ReferenceGroupLabel)
+	if !checkStoreCanRecover(stores, store, s.conf.evictedTS(), cfg.ConsiderPreparingStores, cfg.ComparisonEpsilon, cfg.RecoveryQuorumRatio, cfg.RequireBecomeFastEvent) {
+		return false
+	}
+	if cfg.SymmetricRecovery && checkStoreSlowerThanOthers(stores, store, cfg.ConsiderPreparingStores, cfg.ComparisonMethod, cfg.ComparisonEpsilon) {
+		storeSlowTrendActionStatusGauge.WithLabelValues("recover", "reject_still_slower").Inc()
+		return false
+	}
+	return true
+}
+
+// isCandidateRecovered checks whether the candidate is not slow anymore under
+// the detection mode.
+func (s *evictSlowTrendScheduler) isCandidateRecovered(stores []*core.StoreInfo, store *core.StoreInfo) bool {
+	if s.conf.isLongitudinal() {
+		return !s.conf.regressedAgainstBaseline(store)
+	}
+	cfg := s.conf.Clone()
+	return checkStoreFasterThanOthers(filterReferenceGroup(s.conf.quorumStores(stores), store, cfg.ReferenceGroupLabel), store, cfg.ConsiderPreparingStores, cfg.ComparisonEpsilon)
+}
+
+// isCandidateSlow checks whether the candidate is still slow under the
+// detection mode.
+func (s *evictSlowTrendScheduler) isCandidateSlow(store *core.StoreInfo) bool {
+	if s.conf.hasSustainedWriteStall(store.GetID()) {
+		return true
+	}
+	if s.conf.isLongitudinal() {
+		return s.conf.regressedAgainstBaseline(store)
+	}
+	cfg := s.conf.Clone()
+	return matchSlowTrendPattern(store, cfg.ResultFieldsOptional, cfg.ComparisonEpsilon)
 }
 
 func (s *evictSlowTrendScheduler) IsScheduleAllowed(cluster sche.SchedulerCluster) bool {
 	if s.conf.evictedStore() == 0 {
 		return true
 	}
-	allowed := s.OpController.OperatorCount(operator.OpLeader) < cluster.GetSchedulerConfig().GetLeaderScheduleLimit()
+	var allowed bool
+	if limit := s.conf.evictOperatorLimit(); limit > 0 {
+		// Only count the operators of the scheduler, so that the eviction is not
+		// starved by other schedulers.
+		var count uint64
+		for _, op := range s.OpController.GetOperatorsOfKind(operator.OpLeader) {
+			if op.Desc() == s.GetType() {
+				count++
+			}
+		}
+		allowed = count < limit
+	} else {
+		allowed = s.OpController.OperatorCount(operator.OpLeader) < cluster.GetSchedulerConfig().GetLeaderScheduleLimit()
+	}
 	if !allowed {
 		operator.OperatorLimitCounter.WithLabelValues(s.GetType(), operator.OpLeader.String()).Inc()
 	}
@@ -385,110 +3582,431 @@ func (s *evictSlowTrendScheduler) IsScheduleAllowed(cluster sche.SchedulerCluste
 }
 
 func (s *evictSlowTrendScheduler) Schedule(cluster sche.SchedulerCluster, _ bool) ([]*operator.Operator, []plan.Plan) {
+	if !s.isTraceEnabled() {
+		return s.schedule(cluster)
+	}
+	s.trace = &SlowTrendTrace{}
+	before := snapshotActionStatus()
+	ops, plans := s.schedule(cluster)
+	s.trace.finish(before, snapshotActionStatus(), len(ops))
+	s.setLastTrace(s.trace)
+	s.trace = nil
+	return ops, plans
+}
+
+// schedule runs a tick of the scheduler, the branches taken are recorded in
+// the trace if it's enabled.
+func (s *evictSlowTrendScheduler) schedule(cluster sche.SchedulerCluster) ([]*operator.Operator, []plan.Plan) {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	defer func(start time.Time) {
+		storeSlowTrendScheduleDurationHistogram.Observe(time.Since(start).Seconds())
+	}(time.Now())
+	s.observeStateDuration()
+	if err := s.conf.retryPersist(); err != nil {
+		log.Info("evict-slow-trend-scheduler retry persisting config failed", errs.ZapError(err))
+		storeSlowTrendActionStatusGauge.WithLabelValues("persist", "retry_err").Inc()
+		s.trace.branch("persist_retry_err")
+		s.recordError(err)
+	}
+	s.rampReadmission(cluster)
 
 	var ops []*operator.Operator
+	if s.conf.isPaused() {
+		evictSlowTrendPausedCounter.Inc()
+		s.trace.branch("paused")
+		return ops, nil
+	}
+	if s.conf.inMaintenanceWindow() {
+		// Keep the existing evictions as they are.
+		evictSlowTrendMaintenanceCounter.Inc()
+		s.trace.branch("maintenance_window")
+		return ops, nil
+	}
+	// The candidate may have been evicted if the config is edited by hand, drop
+	// it to avoid processing the store twice.
+	if storeID := s.conf.dropEvictedCandidate(); storeID != 0 {
+		log.Warn("slow store candidate by trend has been evicted already, drop it", zap.Uint64("store-id", storeID))
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "canceled_already_evicted").Inc()
+		slowTrendCandidateCanceledCounter.Inc()
+		slowTrendCandidateExitAlreadyEvictedCounter.Inc()
+		s.trace.branch("drop_evicted_candidate")
+		s.lifecycle.end("canceled")
+	}
+	// Fetch the stores only once in a tick, it's costly in a large cluster.
+	stores := cluster.GetStores()
+	s.conf.updateTrendFirstSeen(stores)
+	stores = s.conf.maskImmatureTrends(stores)
+	s.conf.updateWriteStallStates(stores)
+	s.conf.recordScan(stores)
+	s.conf.updateConfidences(stores)
 
-	if s.conf.evictedStore() != 0 {
-		store := cluster.GetStore(s.conf.evictedStore())
+	if evictedStoreID := s.conf.evictedStore(); evictedStoreID != 0 {
+		s.trace.branch("evicted_store")
+		store := cluster.GetStore(evictedStoreID)
+		reason := evictCleanupReasonRemoved
+		s.updateRecoveryProgress(stores, evictedStoreID, store)
 		if store == nil || store.IsRemoved() {
 			// Previous slow store had been removed, remove the scheduler and check
 			// slow node next time.
-			log.Info("store evicted by slow trend has been removed", zap.Uint64("store-id", store.GetID()))
+			log.Info("store evicted by slow trend has been removed", zap.Uint64("store-id", evictedStoreID),
+				zap.String("reason", s.conf.evictedReason(evictedStoreID)))
 			storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_removed").Inc()
-		} else if checkStoreCanRecover(cluster, store) && s.conf.readyForRecovery() {
-			log.Info("store evicted by slow trend has been recovered", zap.Uint64("store-id", store.GetID()))
+			s.trace.branch("evicted_store_removed")
+			s.lifecycle.end("removed")
+		} else if count, minStores := countStoresForEvicting(stores), s.conf.minStoresForEvicting(); count < minStores {
+			// It's dangerous to keep draining the leaders in a shrunken cluster.
+			log.Warn("stores are removed to too few during the eviction by slow trend, halt it", zap.Uint64("store-id", evictedStoreID),
+				zap.Int("store-count", count), zap.Int("min-stores", minStores))
+			storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_below_min_stores").Inc()
+			s.trace.branch("evicted_store_below_min_stores")
+			s.lifecycle.end("below-min-stores")
+			reason = evictCleanupReasonBelowMinStores
+		} else if store.IsRemoving() && s.conf.Clone().OfflineEvictedStorePolicy == offlineEvictedStorePolicyStop {
+			// Let the decommission proceed without the eviction.
+			log.Info("store evicted by slow trend is going offline, stop managing it", zap.Uint64("store-id", evictedStoreID),
+				zap.String("reason", s.conf.evictedReason(evictedStoreID)))
+			storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_offline").Inc()
+			s.trace.branch("evicted_store_offline")
+			s.lifecycle.end("offline")
+			reason = evictCleanupReasonOffline
+		} else if s.conf.inEvictSettlePeriod() {
+			s.conf.logRoutine("store evicted by slow trend is settling, skip checking its recovery", zap.Uint64("store-id", evictedStoreID))
+			storeSlowTrendActionStatusGauge.WithLabelValues("evict", "continue_settling").Inc()
+			s.trace.branch("evicted_store_settling")
+			return s.scheduleEvictLeader(cluster), nil
+		} else if s.conf.observeRecoveryTick(s.isEvictedStoreRecovered(stores, store) && !s.conf.hasSustainedWriteStall(store.GetID())) &&
+			s.conf.readyForRecovery() {
+			s.conf.getLogger().Info("store evicted by slow trend has been recovered", zap.Uint64("store-id", store.GetID()),
+				zap.String("reason", s.conf.evictedReason(store.GetID())))
 			storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_recovered").Inc()
+			s.trace.branch("evicted_store_recovered")
+			s.lifecycle.end("recovered")
+			reason = evictCleanupReasonRecovered
 		} else {
+			s.conf.logRoutine("store evicted by slow trend is still evicted", zap.Uint64("store-id", evictedStoreID),
+				zap.String("reason", s.conf.evictedReason(evictedStoreID)))
 			storeSlowTrendActionStatusGauge.WithLabelValues("evict", "continue").Inc()
+			s.trace.branch("evicted_store_still_evicted")
 			return s.scheduleEvictLeader(cluster), nil
 		}
-		s.cleanupEvictLeader(cluster)
+		s.cleanupEvictLeader(cluster, reason)
+		if reason == evictCleanupReasonRecovered && s.conf.Clone().RebalanceOnRecover {
+			s.trace.branch("transfer_leaders_back")
+			ops = s.scheduleTransferLeaderBack(cluster, evictedStoreID)
+		}
 		return ops, nil
 	}
 
+	// The existing evictions above still recover with the contradictory config,
+	// but no store is newly captured or evicted.
+	if err := s.conf.contradiction(); err != nil {
+		s.conf.logRoutine("the config of slow trend is contradictory, skip capturing slow stores", errs.ZapError(err))
+		evictSlowTrendContradictoryCounter.Inc()
+		s.trace.branch("contradictory_config")
+		return ops, nil
+	}
 	candFreshCaptured := false
 	if s.conf.candidate() == 0 {
-		candidate := chooseEvictCandidate(cluster, s.conf.lastCapturedCandidate())
+		s.trace.branch("select_candidate")
+		if isRestoring(cluster) {
+			s.conf.logRoutine("snapshot restore is in progress, defer capturing slow store candidate by trend")
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_restore_in_progress").Inc()
+			s.trace.branch("restore_in_progress")
+			return ops, nil
+		}
+		candidate := s.selector.SelectCandidate(cluster, stores)
+		// Only for the tests, it forces the store to be captured regardless of
+		// the selection, the confirmation, eviction and recovery still apply.
+		failpoint.Inject("forceSlowTrendCandidate", func(val failpoint.Value) {
+			if storeID, ok := val.(int); ok {
+				if store := cluster.GetStore(uint64(storeID)); store != nil {
+					s.trace.branch("candidate_forced")
+					candidate = store
+				}
+			}
+		})
+		if candidate != nil && s.conf.inCandidateCancelCooldown(candidate.GetID()) {
+			s.conf.logRoutine("slow store candidate by trend was canceled recently, skip re-capturing it", zap.Uint64("store-id", candidate.GetID()))
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_cancel_cooldown").Inc()
+			s.trace.branch("candidate_cancel_cooldown")
+			return ops, nil
+		}
 		if candidate != nil {
 			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "captured").Inc()
+			s.trace.branch("candidate_captured")
 			s.conf.captureCandidate(candidate.GetID())
+			s.conf.recordCaptureSnapshot(candidate)
+			s.lifecycle.start(candidate.GetID())
 			candFreshCaptured = true
 		}
 	} else {
+		s.conf.logRoutine("slow store candidate by trend is still pending", zap.Uint64("store-id", s.conf.candidate()))
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "continue").Inc()
+		s.trace.branch("candidate_pending")
 	}
 
 	slowStoreID := s.conf.candidate()
 	if slowStoreID == 0 {
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none").Inc()
+		s.trace.branch("no_candidate")
+		s.autoDisableIfIdle()
 		return ops, nil
 	}
 
 	slowStore := cluster.GetStore(slowStoreID)
-	if !candFreshCaptured && checkStoreFasterThanOthers(cluster, slowStore) {
+	if !candFreshCaptured && !s.conf.hasSustainedWriteStall(slowStoreID) && s.isCandidateRecovered(stores, slowStore) {
 		s.conf.popCandidate(false)
 		log.Info("slow store candidate by trend has been cancel", zap.Uint64("store-id", slowStoreID))
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "canceled_too_faster").Inc()
+		s.conf.recordCandidateCancel(slowStoreID)
+		slowTrendCandidateCanceledCounter.Inc()
+		slowTrendCandidateExitTooFasterCounter.Inc()
+		s.trace.branch("candidate_canceled_too_faster")
+		s.lifecycle.end("canceled")
 		return ops, nil
 	}
-	if slowStoreRecordTS := s.conf.captureTS(); !checkStoresAreUpdated(cluster, slowStoreID, slowStoreRecordTS) {
-		log.Info("slow store candidate waiting for other stores to update heartbeats", zap.Uint64("store-id", slowStoreID))
+	if !s.conf.candidateConfirmed() {
+		if !s.conf.candidateSampleDue() {
+			s.conf.logRoutine("slow store candidate waiting for the next sample", zap.Uint64("store-id", slowStoreID))
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "wait_confirmation").Inc()
+			s.trace.branch("candidate_wait_confirmation")
+			return ops, nil
+		}
+		if !s.isCandidateSlow(slowStore) {
+			s.conf.popCandidate(false)
+			log.Info("slow store candidate by trend has been cancel: it's not slow in the next sample", zap.Uint64("store-id", slowStoreID))
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "canceled_not_confirmed").Inc()
+			s.conf.recordCandidateCancel(slowStoreID)
+			slowTrendCandidateCanceledCounter.Inc()
+			slowTrendCandidateExitNotConfirmedCounter.Inc()
+			s.trace.branch("candidate_canceled_not_confirmed")
+			s.lifecycle.end("canceled")
+			return ops, nil
+		}
+		s.conf.addCandidateSample()
+		if !s.conf.candidateConfirmed() {
+			s.conf.logRoutine("slow store candidate waiting for the next sample", zap.Uint64("store-id", slowStoreID))
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "wait_confirmation").Inc()
+			s.trace.branch("candidate_wait_confirmation")
+			return ops, nil
+		}
+	}
+	if slowStoreRecordTS := s.conf.captureTS(); !checkStoresAreUpdated(s.conf.quorumStores(stores), slowStoreID, slowStoreRecordTS, s.conf.Clone().ConsiderPreparingStores) {
+		s.conf.logRoutine("slow store candidate waiting for other stores to update heartbeats", zap.Uint64("store-id", slowStoreID))
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "wait").Inc()
+		s.trace.branch("candidate_wait_heartbeats")
 		return ops, nil
 	}
 
 	candCapturedSecs := s.conf.candidateCapturedSecs()
-	log.Info("detected slow store by trend, start to evict leaders",
+	s.conf.getLogger().Info("detected slow store by trend, start to evict leaders",
 		zap.Uint64("store-id", slowStoreID),
 		zap.Uint64("candidate-captured-secs", candCapturedSecs))
 	storeSlowTrendMiscGauge.WithLabelValues("candidate", "captured_secs").Set(float64(candCapturedSecs))
 	if err := s.prepareEvictLeader(cluster, s.conf.popCandidate(true)); err != nil {
 		log.Info("prepare for evicting leader by slow trend failed", zap.Error(err), zap.Uint64("store-id", slowStoreID))
 		storeSlowTrendActionStatusGauge.WithLabelValues("evict", "prepare_err").Inc()
+		slowTrendCandidateCanceledCounter.Inc()
+		slowTrendCandidateExitPrepareFailedCounter.Inc()
+		s.trace.branch("evict_prepare_err")
+		s.lifecycle.end("canceled")
 		return ops, nil
 	}
 	storeSlowTrendActionStatusGauge.WithLabelValues("evict", "start").Inc()
+	slowTrendCandidateEvictedCounter.Inc()
+	slowTrendCandidateExitEvictedCounter.Inc()
+	s.lifecycle.event("evicted")
+	s.conf.recordEvictionHistory(slowStore)
+	s.trace.branch("evict_start")
 	return s.scheduleEvictLeader(cluster), nil
 }
 
-func newEvictSlowTrendScheduler(opController *operator.Controller, conf *evictSlowTrendSchedulerConfig) Scheduler {
+func newEvictSlowTrendScheduler(opController *operator.Controller, conf *evictSlowTrendSchedulerConfig, options ...EvictSlowTrendCreateOption) Scheduler {
 	handler := newEvictSlowTrendHandler(conf)
-	return &evictSlowTrendScheduler{
+	s := &evictSlowTrendScheduler{
 		BaseScheduler: NewBaseScheduler(opController),
 		conf:          conf,
 		handler:       handler,
+		selector:      &trendCandidateSelector{conf: conf},
+		lifecycle:     &evictionLifecycle{tracer: noopEvictionTracer{}, notifier: newWebhookNotifier(conf)},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// NewEvictSlowTrendSchedulerWithConfig creates an evict-slow-trend scheduler
+// with the config seeded from the given JSON instead of the storage, e.g., the
+// thresholds supplied by a templating system at creation time. The config is
+// persisted once it's validated, and it's managed as usual afterwards.
+func NewEvictSlowTrendSchedulerWithConfig(opController *operator.Controller, storage endpoint.ConfigStorage, config []byte, options ...EvictSlowTrendCreateOption) (Scheduler, error) {
+	conf := initEvictSlowTrendSchedulerConfig(storage)
+	if err := DecodeConfig(config, conf); err != nil {
+		return nil, err
+	}
+	conf.Lock()
+	defer conf.Unlock()
+	if err := conf.expandProfileLocked(config); err != nil {
+		return nil, err
+	}
+	if err := conf.validateLocked(); err != nil {
+		return nil, err
+	}
+	if err := conf.persistLocked(); err != nil {
+		return nil, err
+	}
+	conf.cluster = opController.GetCluster()
+	return newEvictSlowTrendScheduler(opController, conf, options...), nil
+}
+
+// EvictSlowTrendCreateOption is used to create a scheduler with an option.
+type EvictSlowTrendCreateOption func(s *evictSlowTrendScheduler)
+
+// WithEvictSlowTrendCandidateSelector sets the candidate selector for the scheduler.
+func WithEvictSlowTrendCandidateSelector(selector CandidateSelector) EvictSlowTrendCreateOption {
+	return func(s *evictSlowTrendScheduler) {
+		s.selector = selector
+	}
+}
+
+// WithEvictSlowTrendTracer sets the tracer of the eviction lifecycle for the scheduler.
+func WithEvictSlowTrendTracer(tracer EvictionTracer) EvictSlowTrendCreateOption {
+	return func(s *evictSlowTrendScheduler) {
+		s.lifecycle.tracer = tracer
+	}
+}
+
+// alertSlowStore alerts the slow store whose eviction is disabled for the kind
+// of slowness, instead of capturing it as a candidate.
+func alertSlowStore(store *core.StoreInfo, kind string, counter prometheus.Counter) {
+	counter.Inc()
+	storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "alert_"+kind).Inc()
+	log.Warn("evict-slow-trend-scheduler detected slow store, but the eviction is disabled for the kind of slowness",
+		zap.Uint64("store-id", store.GetID()),
+		zap.String("kind", kind))
+}
+
+// defaultAffectedStoreRatioThreshold is the default of the scheduler config
+// `slow-store-evicting-affected-store-ratio-threshold`, it's used when the
+// scheduler config is not available.
+const defaultAffectedStoreRatioThreshold = 0.3
+
+var (
+	nilStoreConfigLogOnce     sync.Once
+	nilSchedulerConfigLogOnce sync.Once
+)
+
+// isRaftKV2Cluster checks whether the engine of the cluster is raft-kv2, the
+// store config may be not available in the early startup, and the cluster is
+// regarded as raft-kv1 then.
+func isRaftKV2Cluster(cluster sche.SchedulerCluster) bool {
+	storeConfig := cluster.GetStoreConfig()
+	if storeConfig == nil {
+		nilStoreConfigLogOnce.Do(func() {
+			log.Warn("evict-slow-trend-scheduler found the store config not available, regard the cluster as raft-kv1")
+		})
+		return false
+	}
+	return storeConfig.IsRaftKV2()
+}
+
+// affectedStoreRatioThreshold returns the ratio of the stores which should be
+// affected by the slow store, the default is used if the scheduler config is
+// not available in the early startup.
+func affectedStoreRatioThreshold(cluster sche.SchedulerCluster) float64 {
+	schedulerConfig := cluster.GetSchedulerConfig()
+	if schedulerConfig == nil {
+		nilSchedulerConfigLogOnce.Do(func() {
+			log.Warn("evict-slow-trend-scheduler found the scheduler config not available, use the default affected store ratio",
+				zap.Float64("ratio", defaultAffectedStoreRatioThreshold))
+		})
+		return defaultAffectedStoreRatioThreshold
 	}
+	return schedulerConfig.GetSlowStoreEvictingAffectedStoreRatioThreshold()
 }
 
-func chooseEvictCandidate(cluster sche.SchedulerCluster, lastEvictCandidate *slowCandidate) (slowStore *core.StoreInfo) {
-	isRaftKV2 := cluster.GetStoreConfig().IsRaftKV2()
+func chooseEvictCandidate(cluster sche.SchedulerCluster, stores []*core.StoreInfo, conf *evictSlowTrendSchedulerConfig, lastEvictCandidate *slowCandidate) (slowStore *core.StoreInfo) {
+	allStores := stores
+	isRaftKV2 := isRaftKV2Cluster(cluster)
 	failpoint.Inject("mockRaftKV2", func() {
 		isRaftKV2 = true
 	})
-	stores := cluster.GetStores()
-	if len(stores) < 3 {
+	if countStoresForEvicting(stores) < conf.minStoresForEvicting() {
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_too_few").Inc()
 		return
 	}
+	if !conf.hasEvictionBudget() {
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_budget_exhausted").Inc()
+		return
+	}
+	// The order of the stores is not guaranteed, sort them by ID so that the
+	// candidate is chosen deterministically when the slow trends tie exactly.
+	stores = sortStoresByID(conf.quorumStores(stores))
+	cfg := conf.Clone()
+	if cfg.MinClusterLeaderCount > 0 {
+		var leaderCount uint64
+		for _, store := range stores {
+			leaderCount += uint64(store.GetLeaderCount())
+		}
+		if leaderCount < cfg.MinClusterLeaderCount {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_too_few_leaders").Inc()
+			return
+		}
+	}
+	if cfg.DetectionMode == slowTrendDetectionModeLongitudinal {
+		return chooseEvictCandidateByBaseline(conf, stores)
+	}
+	considerPreparing, epsilon := cfg.ConsiderPreparingStores, cfg.ComparisonEpsilon
+	observeSkippedStores(allStores, considerPreparing, cfg.EngineFilter)
 
 	var candidates []*core.StoreInfo
 	var affectedStoreCount int
+	var causeValues []float64
+	// The candidates captured by the tail latency, they may look normal in the
+	// slow trend which is the average.
+	tailLatencyCandidates := make(map[uint64]struct{})
 	for _, store := range stores {
-		if store.IsRemoved() {
+		if !isStoreEligible(store, considerPreparing) {
+			continue
+		}
+		if !matchEngineFilter(store, cfg.EngineFilter) {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_engine_filtered").Inc()
+			continue
+		}
+		if tailLatency, ok := getTailLatency(store); ok && cfg.TailLatencyThreshold > 0 && tailLatency > cfg.TailLatencyThreshold {
+			candidates = append(candidates, store)
+			tailLatencyCandidates[store.GetID()] = struct{}{}
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_tail_latency").Inc()
+			log.Info("evict-slow-trend-scheduler pre-captured candidate by tail latency",
+				zap.Uint64("store-id", store.GetID()),
+				zap.Uint64("tail-latency", tailLatency))
 			continue
 		}
-		if !(store.IsPreparing() || store.IsServing()) {
+		if conf.hasSustainedWriteStall(store.GetID()) {
+			// The write stall is not a part of the slow trend, but a store with a
+			// sustained write stall should not hold leaders either.
+			candidates = append(candidates, store)
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_write_stall").Inc()
+			log.Info("evict-slow-trend-scheduler pre-captured candidate by write stall",
+				zap.Uint64("store-id", store.GetID()))
 			continue
 		}
 		if slowTrend := store.GetSlowTrend(); slowTrend != nil {
-			if slowTrend.ResultRate < -alterEpsilon {
+			causeValues = append(causeValues, slowTrend.CauseValue)
+			causeOnly := cfg.ResultFieldsOptional && isSlowTrendResultUnpopulated(slowTrend)
+			if isStoreAffected(slowTrend, cfg.ResultFieldsOptional, epsilon) {
 				affectedStoreCount += 1
 			}
 			// For the cases of disk io jitters.
 			// Normally, if there exists jitters on disk io or network io, the slow store must have a descending
 			// trend on QPS and ascending trend on duration. So, the slowTrend must match the following pattern.
-			if slowTrend.CauseRate > alterEpsilon && slowTrend.ResultRate < -alterEpsilon {
+			if slowTrend.CauseRate > epsilon && slowTrend.ResultRate < -math.Max(epsilon, cfg.MinResultRateDrop) {
+				if !cfg.EnableDiskSlowEvict {
+					alertSlowStore(store, "disk", slowTrendDiskSlowAlertCounter)
+					continue
+				}
+				slowTrendDiskSlowCaptureCounter.Inc()
 				candidates = append(candidates, store)
 				storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add").Inc()
 				log.Info("evict-slow-trend-scheduler pre-captured candidate",
@@ -497,16 +4015,38 @@ func chooseEvictCandidate(cluster sche.SchedulerCluster, lastEvictCandidate *slo
 					zap.Float64("result-rate", slowTrend.ResultRate),
 					zap.Float64("cause-value", slowTrend.CauseValue),
 					zap.Float64("result-value", slowTrend.ResultValue))
-			} else if isRaftKV2 && slowTrend.CauseRate > alterEpsilon {
+			} else if causeOnly && slowTrend.CauseRate > epsilon {
+				// Some TiKV versions do not report the result fields, so only the cause
+				// fields can be used to judge whether the store is slow.
+				if !cfg.EnableDiskSlowEvict {
+					alertSlowStore(store, "disk", slowTrendDiskSlowAlertCounter)
+					continue
+				}
+				slowTrendDiskSlowCaptureCounter.Inc()
+				candidates = append(candidates, store)
+				storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_cause_only").Inc()
+				log.Info("evict-slow-trend-scheduler pre-captured candidate by cause only",
+					zap.Uint64("store-id", store.GetID()),
+					zap.Float64("cause-rate", slowTrend.CauseRate),
+					zap.Float64("cause-value", slowTrend.CauseValue))
+			} else if isRaftKV2 && slowTrend.CauseRate > epsilon {
 				// Meanwhile, if the store was previously experiencing slowness in the `Duration` dimension, it should
 				// re-check whether this node is still encountering network I/O-related jitters. And If this node matches
 				// the last identified candidate, it indicates that the node is still being affected by delays in network I/O,
 				// and consequently, it should be re-designated as slow once more.
 				// Prerequisite: `raft-kv2` engine has the ability to percept the slow trend on network io jitters.
 				// TODO: maybe make it compatible to `raft-kv` later.
-				if lastEvictCandidate != nil && lastEvictCandidate.storeID == store.GetID() && DurationSinceAsSecs(lastEvictCandidate.recoverTS) <= minReCheckDurationGap {
+				// The zero `RecoverTS` means the last candidate was never evicted or
+				// recovered, so it's not re-checked.
+				if lastEvictCandidate != nil && lastEvictCandidate.StoreID == store.GetID() &&
+					!lastEvictCandidate.RecoverTS.IsZero() && conf.secsSince(lastEvictCandidate.RecoverTS) <= minReCheckDurationGap {
+					if !cfg.EnableNetworkSlowEvict {
+						alertSlowStore(store, "network", slowTrendNetworkSlowAlertCounter)
+						continue
+					}
+					slowTrendNetworkSlowCaptureCounter.Inc()
 					candidates = append(candidates, store)
-					storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add").Inc()
+					storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_kv2_jitter").Inc()
 					log.Info("evict-slow-trend-scheduler pre-captured candidate in raft-kv2 cluster",
 						zap.Uint64("store-id", store.GetID()),
 						zap.Float64("cause-rate", slowTrend.CauseRate),
@@ -517,26 +4057,114 @@ func chooseEvictCandidate(cluster sche.SchedulerCluster, lastEvictCandidate *slo
 			}
 		}
 	}
+	affectedStoreTotal := len(stores)
+	if cfg.AffectedRatioOverEligible {
+		affectedStoreTotal = countEligibleStores(stores, cfg.ConsiderPreparingStores)
+	}
+	affectedRatio := affectedStoreRatioThreshold(cluster)
+	if cfg.AffectedStoreRatioThreshold > 0 {
+		affectedRatio = cfg.AffectedStoreRatioThreshold
+	}
+	affectedStoreThreshold := int(float64(affectedStoreTotal) * affectedRatio)
+	storeSlowTrendMiscGauge.WithLabelValues("store", "affected_count").Set(float64(affectedStoreCount))
+	storeSlowTrendMiscGauge.WithLabelValues("store", "affected_threshold").Set(float64(affectedStoreThreshold))
 	if len(candidates) == 0 {
+		if len(causeValues) == 0 {
+			// None of the stores reports the slow trend, e.g., the TiKV version
+			// is too old, so the scheduler can not work at all.
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_no_trend_data").Inc()
+			return
+		}
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_no_fit").Inc()
 		return
 	}
+	if candidates = filterHostLevelSlowCandidates(candidates); len(candidates) == 0 {
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_host_level_slow").Inc()
+		return
+	}
+	if ceiling := cfg.ClusterSlowCauseValueCeiling; ceiling > 0 {
+		if median := calcMedian(causeValues); median > ceiling {
+			log.Info("evict-slow-trend-scheduler skip capturing candidate: the whole cluster is slow",
+				zap.Float64("median-cause-value", median),
+				zap.Float64("ceiling", ceiling))
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_cluster_wide_slow").Inc()
+			return
+		}
+	}
+	if cfg.WarmupDuration > 0 {
+		warmedUp := filterCandidatesByUptime(candidates, time.Duration(cfg.WarmupDuration)*time.Second)
+		storeSlowTrendSkippedStoresGauge.WithLabelValues(skipReasonWarmingUp).Set(float64(len(candidates) - len(warmedUp)))
+		candidates = warmedUp
+		if len(candidates) == 0 {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_warming_up").Inc()
+			return
+		}
+	}
+	if cfg.MinUsedSpaceRatio > 0 {
+		candidates = filterCandidatesByUsedSpace(candidates, cfg.MinUsedSpaceRatio)
+		if len(candidates) == 0 {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_space_ok").Inc()
+			return
+		}
+	}
+	if cfg.FailureDomainLabel != "" {
+		candidates = filterCandidatesByFailureDomain(candidates, stores, cfg.FailureDomainLabel)
+		if len(candidates) == 0 {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_same_failure_domain").Inc()
+			return
+		}
+	}
 	// TODO: Calculate to judge if one store is way slower than the others
-	if len(candidates) > 1 {
+	if len(candidates) > 1 && cfg.UseSlowScore {
+		candidates = filterCandidatesBySlowScore(candidates)
+	}
+	if len(candidates) > 1 && cfg.PickWorstCandidate {
+		sort.Slice(candidates, func(i, j int) bool {
+			return compareSlowTrendSeverity(candidates[i], candidates[j]) < 0
+		})
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "pick_worst").Inc()
+		candidates = candidates[:1]
+	}
+	if len(candidates) != 1 {
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_too_many").Inc()
 		return
 	}
 
 	store := candidates[0]
+	impactScore := calcImpactScore(stores, store, considerPreparing)
+	storeSlowTrendMiscGauge.WithLabelValues("candidate", "impact_score").Set(impactScore)
+	if impactScore < cfg.MinImpactScore {
+		log.Info("evict-slow-trend-scheduler failed to confirm candidate: its impact is too low",
+			zap.Uint64("store-id", store.GetID()),
+			zap.Float64("impact-score", impactScore))
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_low_impact").Inc()
+		return
+	}
+	if conf.hasSustainedWriteStall(store.GetID()) {
+		log.Info("evict-slow-trend-scheduler captured candidate by write stall", zap.Uint64("store-id", store.GetID()))
+		return store
+	}
+	if _, ok := tailLatencyCandidates[store.GetID()]; ok {
+		log.Info("evict-slow-trend-scheduler captured candidate by tail latency", zap.Uint64("store-id", store.GetID()))
+		return store
+	}
+	if !conf.isConfident(store.GetID()) {
+		log.Info("evict-slow-trend-scheduler failed to confirm candidate: its confidence is too low", zap.Uint64("store-id", store.GetID()))
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_low_confidence").Inc()
+		return
+	}
 
-	affectedStoreThreshold := int(float64(len(stores)) * cluster.GetSchedulerConfig().GetSlowStoreEvictingAffectedStoreRatioThreshold())
+	if cfg.RegionAwareAffectedCount {
+		affectedStoreCount = countAffectedRegionPeerStores(cluster, store, cfg.ResultFieldsOptional, epsilon)
+		storeSlowTrendMiscGauge.WithLabelValues("store", "affected_count").Set(float64(affectedStoreCount))
+	}
 	if affectedStoreCount < affectedStoreThreshold {
 		log.Info("evict-slow-trend-scheduler failed to confirm candidate: it only affect a few stores", zap.Uint64("store-id", store.GetID()))
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_affect_a_few").Inc()
 		return
 	}
 
-	if !checkStoreSlowerThanOthers(cluster, store) {
+	if !checkStoreSlowerThanOthers(filterReferenceGroup(stores, store, cfg.ReferenceGroupLabel), store, cfg.ConsiderPreparingStores, cfg.ComparisonMethod, cfg.ComparisonEpsilon) {
 		log.Info("evict-slow-trend-scheduler failed to confirm candidate: it's not slower than others", zap.Uint64("store-id", store.GetID()))
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_not_slower").Inc()
 		return
@@ -547,19 +4175,446 @@ func chooseEvictCandidate(cluster sche.SchedulerCluster, lastEvictCandidate *slo
 	return store
 }
 
-func checkStoresAreUpdated(cluster sche.SchedulerCluster, slowStoreID uint64, slowStoreRecordTS time.Time) bool {
-	stores := cluster.GetStores()
+// chooseEvictCandidateByBaseline chooses the store which regressed most against
+// its own baseline, so that the slow store can be found even if all stores
+// become slow together.
+func chooseEvictCandidateByBaseline(conf *evictSlowTrendSchedulerConfig, stores []*core.StoreInfo) (slowStore *core.StoreInfo) {
+	cfg := conf.Clone()
+	regressionRatio, considerPreparing := cfg.BaselineRegressionRatio, cfg.ConsiderPreparingStores
+	var maxRatio float64
+	var hasTrendData bool
+	for _, store := range stores {
+		if !isStoreEligible(store, considerPreparing) {
+			continue
+		}
+		if !matchEngineFilter(store, cfg.EngineFilter) {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_engine_filtered").Inc()
+			continue
+		}
+		if conf.hasSustainedWriteStall(store.GetID()) && slowStore == nil {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_write_stall").Inc()
+			slowStore, maxRatio = store, math.MaxFloat64
+		}
+		slowTrend := store.GetSlowTrend()
+		if slowTrend == nil {
+			continue
+		}
+		hasTrendData = true
+		ratio := conf.observeBaseline(store.GetID(), slowTrend.CauseValue)
+		if ratio >= regressionRatio && ratio > maxRatio {
+			slowStore, maxRatio = store, ratio
+		}
+	}
+	if slowStore == nil {
+		if !hasTrendData {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_no_trend_data").Inc()
+			return
+		}
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_no_regression").Inc()
+		return
+	}
+	storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_regression").Inc()
+	log.Info("evict-slow-trend-scheduler captured candidate by baseline",
+		zap.Uint64("store-id", slowStore.GetID()),
+		zap.Float64("regression-ratio", maxRatio))
+	return slowStore
+}
+
+// calcImpactScore calculates the impact of the store by the leaders it holds.
+// Each leader is weighted by its size relative to the average leader size of
+// the cluster, so the score is the number of the average leaders.
+func calcImpactScore(stores []*core.StoreInfo, target *core.StoreInfo, considerPreparing bool) float64 {
+	var totalLeaderSize int64
+	var totalLeaderCount int
+	for _, store := range stores {
+		if !isStoreEligible(store, considerPreparing) {
+			continue
+		}
+		totalLeaderSize += store.GetLeaderSize()
+		totalLeaderCount += store.GetLeaderCount()
+	}
+	if totalLeaderSize <= 0 || totalLeaderCount == 0 {
+		return float64(target.GetLeaderCount())
+	}
+	avgLeaderSize := float64(totalLeaderSize) / float64(totalLeaderCount)
+	return float64(target.GetLeaderSize()) / avgLeaderSize
+}
+
+// isStoreEligible checks whether the store takes part in the slow trend
+// detection.
+func isStoreEligible(store *core.StoreInfo, considerPreparing bool) bool {
+	if store.IsRemoved() {
+		return false
+	}
+	return store.IsServing() || (considerPreparing && store.IsPreparing())
+}
+
+// matchEngineFilter checks whether the engine of the store matches the filter,
+// the empty filter matches all engines.
+func matchEngineFilter(store *core.StoreInfo, engineFilter string) bool {
+	if engineFilter == "" {
+		return true
+	}
+	if store.IsTiFlash() {
+		return engineFilter == core.EngineTiFlash
+	}
+	return engineFilter == core.EngineTiKV
+}
+
+// matchSlowTrendPattern checks whether the slow trend of the store matches the
+// pattern of a slow store.
+func matchSlowTrendPattern(store *core.StoreInfo, resultFieldsOptional bool, epsilon float64) bool {
+	if store == nil || store.GetSlowTrend() == nil {
+		return false
+	}
+	slowTrend := store.GetSlowTrend()
+	if slowTrend.CauseRate <= epsilon {
+		return false
+	}
+	return slowTrend.ResultRate < -epsilon || (resultFieldsOptional && isSlowTrendResultUnpopulated(slowTrend))
+}
+
+// calcMedian returns the median of the given values, 0 if it's empty.
+func calcMedian(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// isSlowTrendResultUnpopulated checks whether the result fields of the slow
+// trend are not reported by the store.
+func isSlowTrendResultUnpopulated(slowTrend *pdpb.SlowTrend) bool {
+	return slowTrend.ResultValue == 0 && slowTrend.ResultRate == 0
+}
+
+// filterCandidatesBySlowScore keeps the candidates with the highest slow score.
+// If none of the candidates is regarded as slow by the slow score, an empty
+// slice will be returned.
+func filterCandidatesBySlowScore(candidates []*core.StoreInfo) []*core.StoreInfo {
+	var maxSlowScore uint64
+	for _, store := range candidates {
+		if slowScore := store.GetSlowScore(); slowScore > maxSlowScore {
+			maxSlowScore = slowScore
+		}
+	}
+	if maxSlowScore <= slowStoreRecoverThreshold {
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_slow_score_normal").Inc()
+		return nil
+	}
+	var filtered []*core.StoreInfo
+	for _, store := range candidates {
+		if store.GetSlowScore() == maxSlowScore {
+			filtered = append(filtered, store)
+		}
+	}
+	return filtered
+}
+
+// getTailLatency returns the tail latency reported by the store, false means the
+// store does not report it.
+func getTailLatency(store *core.StoreInfo) (uint64, bool) {
+	for _, record := range store.GetStoreStats().GetOpLatencies() {
+		if record.GetKey() == tailLatencyRecordKey {
+			return record.GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+// countEligibleStores counts the stores eligible to be compared, e.g., the
+// serving ones.
+func countEligibleStores(stores []*core.StoreInfo, considerPreparing bool) int {
+	count := 0
+	for _, store := range stores {
+		if isStoreEligible(store, considerPreparing) {
+			count++
+		}
+	}
+	return count
+}
+
+// quorumStores returns the stores counting toward the total in the ratio and
+// quorum math. The stores not eligible are skipped in the math anyway, so
+// dropping them only shrinks the denominators.
+func (conf *evictSlowTrendSchedulerConfig) quorumStores(stores []*core.StoreInfo) []*core.StoreInfo {
+	conf.RLock()
+	denominator, considerPreparing := conf.QuorumDenominator, conf.ConsiderPreparingStores
+	conf.RUnlock()
+	if denominator != quorumDenominatorEligible {
+		return stores
+	}
+	eligible := make([]*core.StoreInfo, 0, len(stores))
+	for _, store := range stores {
+		if isStoreEligible(store, considerPreparing) {
+			eligible = append(eligible, store)
+		}
+	}
+	return eligible
+}
+
+// isStoreAffected checks whether the QPS of the store is dropping, or its
+// latency is rising if the result fields are not reported.
+func isStoreAffected(slowTrend *pdpb.SlowTrend, resultFieldsOptional bool, epsilon float64) bool {
+	if slowTrend == nil {
+		return false
+	}
+	causeOnly := resultFieldsOptional && isSlowTrendResultUnpopulated(slowTrend)
+	return slowTrend.ResultRate < -epsilon || (causeOnly && slowTrend.CauseRate > epsilon)
+}
+
+// countAffectedRegionPeerStores counts the slow store and the stores hosting the
+// replicas of its sampled leader regions which are affected, so the stores slow
+// coincidentally without sharing any region with the slow store are ignored.
+func countAffectedRegionPeerStores(cluster sche.SchedulerCluster, slowStore *core.StoreInfo, resultFieldsOptional bool, epsilon float64) int {
+	affected := make(map[uint64]struct{})
+	if isStoreAffected(slowStore.GetSlowTrend(), resultFieldsOptional, epsilon) {
+		affected[slowStore.GetID()] = struct{}{}
+	}
+	for _, region := range cluster.RandLeaderRegions(slowStore.GetID(), []core.KeyRange{core.NewKeyRange("", "")}) {
+		for _, store := range cluster.GetFollowerStores(region) {
+			if isStoreAffected(store.GetSlowTrend(), resultFieldsOptional, epsilon) {
+				affected[store.GetID()] = struct{}{}
+			}
+		}
+	}
+	return len(affected)
+}
+
+// sortStoresByID returns a copy of the stores sorted by ascending store ID.
+func sortStoresByID(stores []*core.StoreInfo) []*core.StoreInfo {
+	sorted := make([]*core.StoreInfo, len(stores))
+	copy(sorted, stores)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetID() < sorted[j].GetID() })
+	return sorted
+}
+
+// storeHost returns the host where the store is, which is the host label if it's
+// set, or the host of its address.
+func storeHost(store *core.StoreInfo) string {
+	if host := store.GetLabelValue(hostLabelKey); host != "" {
+		return host
+	}
+	host, _, err := net.SplitHostPort(store.GetAddress())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// filterHostLevelSlowCandidates drops the candidates co-located on the same host
+// with other candidates. The slowness is likely caused by the host rather than
+// the stores, and evicting the leaders to the siblings doesn't help.
+func filterHostLevelSlowCandidates(candidates []*core.StoreInfo) []*core.StoreInfo {
+	hosts := make(map[string]int)
+	for _, store := range candidates {
+		if host := storeHost(store); host != "" {
+			hosts[host]++
+		}
+	}
+	filtered := make([]*core.StoreInfo, 0, len(candidates))
+	for _, store := range candidates {
+		if host := storeHost(store); host != "" && hosts[host] > 1 {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "skip_host_level_slow").Inc()
+			log.Info("evict-slow-trend-scheduler skip the candidate slow with the co-located stores",
+				zap.Uint64("store-id", store.GetID()),
+				zap.String("host", host))
+			continue
+		}
+		filtered = append(filtered, store)
+	}
+	return filtered
+}
+
+// filterCandidatesByFailureDomain keeps at most one store evicted in each
+// failure domain. The candidates in the domains with evicted stores, either by
+// slow store or slow trend, are dropped, and only the most severe one of the
+// candidates in the same domain is kept. The stores without the label are not
+// restricted.
+func filterCandidatesByFailureDomain(candidates, stores []*core.StoreInfo, label string) []*core.StoreInfo {
+	evictedDomains := make(map[string]struct{})
+	for _, store := range stores {
+		if domain := store.GetLabelValue(label); domain != "" && (store.EvictedAsSlowStore() || store.IsEvictedAsSlowTrend()) {
+			evictedDomains[domain] = struct{}{}
+		}
+	}
+	filtered := make([]*core.StoreInfo, 0, len(candidates))
+	worstInDomain := make(map[string]int)
+	for _, store := range candidates {
+		domain := store.GetLabelValue(label)
+		if domain == "" {
+			filtered = append(filtered, store)
+			continue
+		}
+		if _, ok := evictedDomains[domain]; ok {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "skip_same_failure_domain").Inc()
+			continue
+		}
+		if i, ok := worstInDomain[domain]; ok {
+			storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "skip_same_failure_domain").Inc()
+			if compareSlowTrendSeverity(store, filtered[i]) < 0 {
+				filtered[i] = store
+			}
+			continue
+		}
+		worstInDomain[domain] = len(filtered)
+		filtered = append(filtered, store)
+	}
+	return filtered
+}
+
+// compareSlowTrendSeverity compares the severity of the slow trends of two
+// stores, it returns a negative number if a is more severe than b, and a
+// positive number if b is more severe. The metrics are compared in order:
+//  1. greater `CauseValue`, which means higher latency;
+//  2. greater `CauseRate`, which means the latency rises faster;
+//  3. smaller `ResultRate`, which means the QPS drops faster;
+//  4. smaller `ResultValue`, which means lower QPS.
+//
+// The store without slow trend is the least severe, and the smaller store ID
+// wins the tie, so the result is deterministic.
+func compareSlowTrendSeverity(a, b *core.StoreInfo) int {
+	at, bt := a.GetSlowTrend(), b.GetSlowTrend()
+	switch {
+	case at == nil && bt != nil:
+		return 1
+	case at != nil && bt == nil:
+		return -1
+	case at != nil && bt != nil:
+		if c := compareFloat(bt.CauseValue, at.CauseValue); c != 0 {
+			return c
+		}
+		if c := compareFloat(bt.CauseRate, at.CauseRate); c != 0 {
+			return c
+		}
+		if c := compareFloat(at.ResultRate, bt.ResultRate); c != 0 {
+			return c
+		}
+		if c := compareFloat(at.ResultValue, bt.ResultValue); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a.GetID() < b.GetID():
+		return -1
+	case a.GetID() > b.GetID():
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+const (
+	skipReasonRemoved        = "removed"
+	skipReasonNotServing     = "not_serving"
+	skipReasonEngineFiltered = "engine_filtered"
+	skipReasonNoTrendData    = "no_trend_data"
+	skipReasonWarmingUp      = "warming_up"
+)
+
+// observeSkippedStores updates the number of the stores skipped by each reason
+// in the scan, so that the population of the eligible stores is clear. The
+// stores warming up are observed once the candidates are filtered by uptime.
+func observeSkippedStores(stores []*core.StoreInfo, considerPreparing bool, engineFilter string) {
+	skipped := map[string]int{
+		skipReasonRemoved:        0,
+		skipReasonNotServing:     0,
+		skipReasonEngineFiltered: 0,
+		skipReasonNoTrendData:    0,
+		skipReasonWarmingUp:      0,
+	}
+	for _, store := range stores {
+		switch {
+		case store.IsRemoved():
+			skipped[skipReasonRemoved]++
+		case !isStoreEligible(store, considerPreparing):
+			skipped[skipReasonNotServing]++
+		case !matchEngineFilter(store, engineFilter):
+			skipped[skipReasonEngineFiltered]++
+		case store.GetSlowTrend() == nil:
+			skipped[skipReasonNoTrendData]++
+		}
+	}
+	for reason, count := range skipped {
+		storeSlowTrendSkippedStoresGauge.WithLabelValues(reason).Set(float64(count))
+	}
+}
+
+// filterCandidatesByUptime keeps the candidates which have finished warming up.
+func filterCandidatesByUptime(candidates []*core.StoreInfo, warmupDuration time.Duration) []*core.StoreInfo {
+	var filtered []*core.StoreInfo
+	for _, store := range candidates {
+		if uptime := store.GetUptime(); uptime < warmupDuration {
+			log.Info("evict-slow-trend-scheduler skip candidate: it's warming up",
+				zap.Uint64("store-id", store.GetID()),
+				zap.Duration("uptime", uptime))
+			continue
+		}
+		filtered = append(filtered, store)
+	}
+	return filtered
+}
+
+// filterCandidatesByUsedSpace keeps the candidates whose used space ratio
+// reaches the given ratio.
+func filterCandidatesByUsedSpace(candidates []*core.StoreInfo, minUsedRatio float64) []*core.StoreInfo {
+	var filtered []*core.StoreInfo
+	for _, store := range candidates {
+		if usedRatio := 1 - store.AvailableRatio(); usedRatio < minUsedRatio {
+			log.Info("evict-slow-trend-scheduler skip candidate: it has enough free space",
+				zap.Uint64("store-id", store.GetID()),
+				zap.Float64("used-ratio", usedRatio))
+			continue
+		}
+		filtered = append(filtered, store)
+	}
+	return filtered
+}
+
+// filterReferenceGroup returns the stores in the same reference group as the
+// target, i.e., with the same value of the label. All the stores are returned
+// if the label is not set or the target does not have it.
+func filterReferenceGroup(stores []*core.StoreInfo, target *core.StoreInfo, label string) []*core.StoreInfo {
+	if label == "" {
+		return stores
+	}
+	group := target.GetLabelValue(label)
+	if group == "" {
+		return stores
+	}
+	filtered := make([]*core.StoreInfo, 0, len(stores))
+	for _, store := range stores {
+		if store.GetLabelValue(label) == group {
+			filtered = append(filtered, store)
+		}
+	}
+	return filtered
+}
+
+func checkStoresAreUpdated(stores []*core.StoreInfo, slowStoreID uint64, slowStoreRecordTS time.Time, considerPreparing bool) bool {
 	if len(stores) <= 1 {
 		return false
 	}
 	expected := (len(stores) + 1) / 2
 	updatedStores := 0
 	for _, store := range stores {
-		if store.IsRemoved() {
-			updatedStores += 1
-			continue
-		}
-		if !(store.IsPreparing() || store.IsServing()) {
+		if !isStoreEligible(store, considerPreparing) {
 			updatedStores += 1
 			continue
 		}
@@ -576,20 +4631,114 @@ func checkStoresAreUpdated(cluster sche.SchedulerCluster, slowStoreID uint64, sl
 	return updatedStores >= expected
 }
 
-func checkStoreSlowerThanOthers(cluster sche.SchedulerCluster, target *core.StoreInfo) bool {
-	stores := cluster.GetStores()
+func checkStoreSlowerThanOthers(stores []*core.StoreInfo, target *core.StoreInfo, considerPreparing bool, method string, epsilon float64) bool {
 	expected := (len(stores)*2 + 1) / 3
 	targetSlowTrend := target.GetSlowTrend()
 	if targetSlowTrend == nil {
 		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "check_slower_no_data").Inc()
 		return false
 	}
-	slowerThanStoresNum := 0
+	if method == slowTrendComparisonMedian {
+		return checkStoreSlowerThanMedian(stores, target, considerPreparing, epsilon)
+	}
+	slowerThanStoresNum := countSlowerThanStores(stores, target, considerPreparing, epsilon)
+	storeSlowTrendMiscGauge.WithLabelValues("store", "check_slower_count").Set(float64(slowerThanStoresNum))
+	storeSlowTrendMiscGauge.WithLabelValues("store", "check_slower_expected").Set(float64(expected))
+	return slowerThanStoresNum >= expected
+}
+
+// checkStoreSlowerThanMedian checks whether the `CauseValue` of the target is
+// much greater than the median of the other stores.
+func checkStoreSlowerThanMedian(stores []*core.StoreInfo, target *core.StoreInfo, considerPreparing bool, epsilon float64) bool {
+	causeValues := make([]float64, 0, len(stores))
 	for _, store := range stores {
-		if store.IsRemoved() {
+		if !isStoreEligible(store, considerPreparing) || store.GetID() == target.GetID() {
 			continue
 		}
-		if !(store.IsPreparing() || store.IsServing()) {
+		if slowTrend := store.GetSlowTrend(); slowTrend != nil && slowTrend.CauseValue > epsilon {
+			causeValues = append(causeValues, slowTrend.CauseValue)
+		}
+	}
+	if len(causeValues) == 0 {
+		return false
+	}
+	sort.Float64s(causeValues)
+	median := causeValues[len(causeValues)/2]
+	if len(causeValues)%2 == 0 {
+		median = (causeValues[len(causeValues)/2-1] + median) / 2
+	}
+	storeSlowTrendMiscGauge.WithLabelValues("store", "check_slower_median").Set(median)
+	return target.GetSlowTrend().CauseValue > median*slowerThanMedianRatio
+}
+
+func checkStoreCanRecover(stores []*core.StoreInfo, target *core.StoreInfo, evictedTS time.Time, considerPreparing bool, epsilon, quorumRatio float64, requireBecomeFast bool) bool {
+	// The `become fast` event might not be necessary, and it also has tiny
+	// chances to cause `stuck in evicted` status when this store restarted,
+	// the event might be ignored on tikv side because the detecting windows are
+	// not fully filled yet. Hence, it's only checked if required explicitly.
+	if !checkStoreDataRefreshed(target, evictedTS) {
+		return false
+	}
+	if requireBecomeFast && !checkStoreBecomeFast(target, epsilon) {
+		return false
+	}
+	return checkStoreFasterThanQuorum(stores, target, considerPreparing, epsilon, quorumRatio)
+}
+
+// checkStoreBecomeFast checks whether the store reports the `become fast`
+// event, i.e., its own slow trend is improving.
+func checkStoreBecomeFast(target *core.StoreInfo, epsilon float64) bool {
+	slowTrend := target.GetSlowTrend()
+	if slowTrend == nil || (slowTrend.CauseRate >= -epsilon && slowTrend.ResultRate <= epsilon) {
+		storeSlowTrendActionStatusGauge.WithLabelValues("recover", "reject_no_fast_event").Inc()
+		return false
+	}
+	storeSlowTrendActionStatusGauge.WithLabelValues("recover", "got_fast_event").Inc()
+	return true
+}
+
+// checkStoreDataRefreshed checks whether the store keeps heartbeating and its
+// slow trend has been refreshed since it was evicted, otherwise it may be
+// regarded as recovered by the stale data.
+func checkStoreDataRefreshed(target *core.StoreInfo, evictedTS time.Time) bool {
+	if target.IsDisconnected() || target.GetSlowTrend() == nil || !target.GetLastHeartbeatTS().After(evictedTS) {
+		storeSlowTrendActionStatusGauge.WithLabelValues("recover", "reject_stale_data").Inc()
+		return false
+	}
+	return true
+}
+
+func checkStoreFasterThanOthers(stores []*core.StoreInfo, target *core.StoreInfo, considerPreparing bool, epsilon float64) bool {
+	return checkStoreFasterThanQuorum(stores, target, considerPreparing, epsilon, 0)
+}
+
+// checkStoreFasterThanQuorum checks whether the target is faster than the
+// given ratio of the other stores, 0 means the majority of the stores.
+func checkStoreFasterThanQuorum(stores []*core.StoreInfo, target *core.StoreInfo, considerPreparing bool, epsilon, quorumRatio float64) bool {
+	expected := (len(stores) + 1) / 2
+	if quorumRatio > 0 {
+		expected = int(math.Ceil(float64(len(stores)-1) * quorumRatio))
+	}
+	targetSlowTrend := target.GetSlowTrend()
+	if targetSlowTrend == nil {
+		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "check_faster_no_data").Inc()
+		return false
+	}
+	fasterThanStores := countFasterThanStores(stores, target, considerPreparing, epsilon)
+	storeSlowTrendMiscGauge.WithLabelValues("store", "check_faster_count").Set(float64(fasterThanStores))
+	storeSlowTrendMiscGauge.WithLabelValues("store", "check_faster_expected").Set(float64(expected))
+	return fasterThanStores >= expected
+}
+
+// countSlowerThanStores counts the other stores which the target is slower than.
+func countSlowerThanStores(stores []*core.StoreInfo, target *core.StoreInfo, considerPreparing bool, epsilon float64) int {
+	targetSlowTrend := target.GetSlowTrend()
+	if targetSlowTrend == nil {
+		return 0
+	}
+	slowerThanStoresNum := 0
+	for _, store := range stores {
+		if !isStoreEligible(store, considerPreparing) {
 			continue
 		}
 		if store.GetID() == target.GetID() {
@@ -598,52 +4747,23 @@ func checkStoreSlowerThanOthers(cluster sche.SchedulerCluster, target *core.Stor
 		slowTrend := store.GetSlowTrend()
 		// Use `SlowTrend.ResultValue` at first, but not good, `CauseValue` is better
 		// Greater `CauseValue` means slower
-		if slowTrend != nil && (targetSlowTrend.CauseValue-slowTrend.CauseValue) > alterEpsilon && slowTrend.CauseValue > alterEpsilon {
+		if slowTrend != nil && (targetSlowTrend.CauseValue-slowTrend.CauseValue) > epsilon && slowTrend.CauseValue > epsilon {
 			slowerThanStoresNum += 1
 		}
 	}
-	storeSlowTrendMiscGauge.WithLabelValues("store", "check_slower_count").Set(float64(slowerThanStoresNum))
-	storeSlowTrendMiscGauge.WithLabelValues("store", "check_slower_expected").Set(float64(expected))
-	return slowerThanStoresNum >= expected
-}
-
-func checkStoreCanRecover(cluster sche.SchedulerCluster, target *core.StoreInfo) bool {
-	/*
-		//
-		// This might not be necessary,
-		//   and it also have tiny chances to cause `stuck in evicted`
-		//   status when this store restarted,
-		//   the `become fast` might be ignore on tikv side
-		//   because of the detecting windows are not fully filled yet.
-		// Hence, we disabled this event capturing by now but keep the code here for further checking.
-		//
-
-		// Wait for the evicted store's `become fast` event
-		slowTrend := target.GetSlowTrend()
-		if slowTrend == nil || slowTrend.CauseRate >= 0 && slowTrend.ResultRate <= 0 {
-			storeSlowTrendActionStatusGauge.WithLabelValues("recover.reject:no-event").Inc()
-			return false
-		} else {
-			storeSlowTrendActionStatusGauge.WithLabelValues("recover.judging:got-event").Inc()
-		}
-	*/
-	return checkStoreFasterThanOthers(cluster, target)
+	return slowerThanStoresNum
 }
 
-func checkStoreFasterThanOthers(cluster sche.SchedulerCluster, target *core.StoreInfo) bool {
-	stores := cluster.GetStores()
-	expected := (len(stores) + 1) / 2
+// countFasterThanStores counts the other stores which the target is not
+// obviously slower than.
+func countFasterThanStores(stores []*core.StoreInfo, target *core.StoreInfo, considerPreparing bool, epsilon float64) int {
 	targetSlowTrend := target.GetSlowTrend()
 	if targetSlowTrend == nil {
-		storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "check_faster_no_data").Inc()
-		return false
+		return 0
 	}
 	fasterThanStores := 0
 	for _, store := range stores {
-		if store.IsRemoved() {
-			continue
-		}
-		if !(store.IsPreparing() || store.IsServing()) {
+		if !isStoreEligible(store, considerPreparing) {
 			continue
 		}
 		if store.GetID() == target.GetID() {
@@ -652,16 +4772,28 @@ func checkStoreFasterThanOthers(cluster sche.SchedulerCluster, target *core.Stor
 		slowTrend := store.GetSlowTrend()
 		// Greater `CauseValue` means slower
 		if slowTrend != nil && targetSlowTrend.CauseValue <= slowTrend.CauseValue*1.1 &&
-			slowTrend.CauseValue > alterEpsilon && targetSlowTrend.CauseValue > alterEpsilon {
+			slowTrend.CauseValue > epsilon && targetSlowTrend.CauseValue > epsilon {
 			fasterThanStores += 1
 		}
 	}
-	storeSlowTrendMiscGauge.WithLabelValues("store", "check_faster_count").Set(float64(fasterThanStores))
-	storeSlowTrendMiscGauge.WithLabelValues("store", "check_faster_expected").Set(float64(expected))
-	return fasterThanStores >= expected
+	return fasterThanStores
 }
 
 // DurationSinceAsSecs returns the duration gap since the given startTS, unit: s.
+// It returns 0 if the startTS is zero or in the future, e.g., the clock is
+// skewed after the leader changes. Note the callers have to check the zero
+// startTS by themselves if it shouldn't be regarded as just now.
 func DurationSinceAsSecs(startTS time.Time) uint64 {
-	return uint64(time.Since(startTS).Seconds())
+	return durationBetweenAsSecs(startTS, time.Now())
+}
+
+func durationBetweenAsSecs(startTS, now time.Time) uint64 {
+	if startTS.IsZero() {
+		return 0
+	}
+	duration := now.Sub(startTS)
+	if duration <= 0 {
+		return 0
+	}
+	return uint64(duration.Seconds())
 }
@@ -0,0 +1,218 @@
+// Copyright 2026 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/core"
+	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/pkg/mock/mockconfig"
+	"github.com/tikv/pd/pkg/schedule/hbstream"
+	"github.com/tikv/pd/pkg/schedule/operator"
+	"github.com/tikv/pd/pkg/storage"
+)
+
+// normalSlowTrend is the slow trend reported by a healthy store.
+func normalSlowTrend() *pdpb.SlowTrend {
+	return &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0.0,
+		ResultValue: 5.0e3,
+		ResultRate:  0.0,
+	}
+}
+
+// slowSlowTrend is the slow trend reported by a store with the disk jitters.
+func slowSlowTrend() *pdpb.SlowTrend {
+	return &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+}
+
+// slowTrendTestStore is the declarative setup of a store in the test cluster.
+type slowTrendTestStore struct {
+	id           uint64
+	leaderCount  int
+	slowTrend    *pdpb.SlowTrend
+	preparing    bool
+	heartbeatAge time.Duration
+	labels       []*metapb.StoreLabel
+}
+
+// slowTrendTestStoreOption is used to set up a store in the test cluster.
+type slowTrendTestStoreOption func(*slowTrendTestStore)
+
+// withTestLeaderCount sets the leader count of the store.
+func withTestLeaderCount(leaderCount int) slowTrendTestStoreOption {
+	return func(store *slowTrendTestStore) { store.leaderCount = leaderCount }
+}
+
+// withTestSlowTrend sets the slow trend reported by the store.
+func withTestSlowTrend(slowTrend *pdpb.SlowTrend) slowTrendTestStoreOption {
+	return func(store *slowTrendTestStore) { store.slowTrend = slowTrend }
+}
+
+// withTestPreparing makes the store preparing, e.g., it's just scaled out.
+func withTestPreparing() slowTrendTestStoreOption {
+	return func(store *slowTrendTestStore) { store.preparing = true }
+}
+
+// withTestHeartbeatAge makes the last heartbeat of the store older than now.
+func withTestHeartbeatAge(age time.Duration) slowTrendTestStoreOption {
+	return func(store *slowTrendTestStore) { store.heartbeatAge = age }
+}
+
+// withTestLabel adds a label to the store.
+func withTestLabel(key, value string) slowTrendTestStoreOption {
+	return func(store *slowTrendTestStore) {
+		store.labels = append(store.labels, &metapb.StoreLabel{Key: key, Value: value})
+	}
+}
+
+// slowTrendTestClusterBuilder builds a test cluster for driving the whole
+// Schedule flow of the evict-slow-trend scheduler.
+type slowTrendTestClusterBuilder struct {
+	re     *require.Assertions
+	stores []*slowTrendTestStore
+}
+
+func newSlowTrendTestClusterBuilder(re *require.Assertions) *slowTrendTestClusterBuilder {
+	return &slowTrendTestClusterBuilder{re: re}
+}
+
+// addStore adds a serving store reporting the normal slow trend by default.
+func (b *slowTrendTestClusterBuilder) addStore(id uint64, opts ...slowTrendTestStoreOption) *slowTrendTestClusterBuilder {
+	store := &slowTrendTestStore{id: id, leaderCount: 10, slowTrend: normalSlowTrend()}
+	for _, opt := range opts {
+		opt(store)
+	}
+	b.stores = append(b.stores, store)
+	return b
+}
+
+// build creates the cluster with the stores, and a leader region on each store
+// with the followers on the next two stores.
+func (b *slowTrendTestClusterBuilder) build() *slowTrendTestCluster {
+	Register()
+	ctx, cancel := context.WithCancel(context.Background())
+	tc := mockcluster.NewCluster(ctx, mockconfig.NewTestOptions())
+	stream := hbstream.NewTestHeartbeatStreams(ctx, tc.ID, tc, false)
+	c := &slowTrendTestCluster{
+		Cluster: tc,
+		re:      b.re,
+		cancel:  cancel,
+		oc:      operator.NewController(ctx, tc.GetBasicCluster(), tc.GetSchedulerConfig(), stream),
+		now:     time.Now(),
+	}
+	for _, store := range b.stores {
+		tc.AddLeaderStore(store.id, store.leaderCount)
+		slowTrend := store.slowTrend
+		opts := []core.StoreCreateOption{
+			func(store *core.StoreInfo) { store.GetStoreStats().SlowTrend = slowTrend },
+			core.SetLastHeartbeatTS(c.now.Add(-store.heartbeatAge)),
+		}
+		if !store.preparing {
+			opts = append(opts, core.SetStoreState(metapb.StoreState_Up))
+		}
+		if len(store.labels) > 0 {
+			opts = append(opts, core.SetStoreLabels(store.labels))
+		}
+		tc.PutStore(tc.GetStore(store.id).Clone(opts...))
+	}
+	for i, store := range b.stores {
+		followers := make([]uint64, 0, 2)
+		for j := 1; j <= 2 && j < len(b.stores); j++ {
+			followers = append(followers, b.stores[(i+j)%len(b.stores)].id)
+		}
+		tc.AddLeaderRegion(store.id, store.id, followers...)
+	}
+	// The stores have to heartbeat again to be regarded as updated after the
+	// candidate is captured.
+	c.advance(time.Second)
+	return c
+}
+
+// slowTrendTestCluster is a test double of the cluster with a mocked clock,
+// which is shared with the schedulers created by it.
+type slowTrendTestCluster struct {
+	*mockcluster.Cluster
+	re     *require.Assertions
+	cancel context.CancelFunc
+	oc     *operator.Controller
+	now    time.Time
+}
+
+func (c *slowTrendTestCluster) close() {
+	c.cancel()
+}
+
+// newScheduler creates an evict-slow-trend scheduler driven by the clock of
+// the cluster.
+func (c *slowTrendTestCluster) newScheduler() *evictSlowTrendScheduler {
+	s, err := CreateScheduler(EvictSlowTrendType, c.oc, storage.NewStorageWithMemoryBackend(), ConfigSliceDecoder(EvictSlowTrendType, []string{}))
+	c.re.NoError(err)
+	es, ok := s.(*evictSlowTrendScheduler)
+	c.re.True(ok)
+	es.conf.now = func() time.Time { return c.now }
+	return es
+}
+
+// newBalanceLeaderScheduler creates a balance-leader scheduler to check the
+// leaders can not be moved into the evicted stores.
+func (c *slowTrendTestCluster) newBalanceLeaderScheduler() Scheduler {
+	s, err := CreateScheduler(BalanceLeaderType, c.oc, storage.NewStorageWithMemoryBackend(), ConfigSliceDecoder(BalanceLeaderType, []string{}))
+	c.re.NoError(err)
+	return s
+}
+
+// report updates the slow trend of the store as if it had reported it in a
+// heartbeat right now.
+func (c *slowTrendTestCluster) report(storeID uint64, slowTrend *pdpb.SlowTrend) {
+	store := c.GetStore(storeID)
+	c.re.NotNil(store)
+	c.PutStore(store.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = slowTrend
+	}, core.SetLastHeartbeatTS(c.now)))
+}
+
+// heartbeat refreshes the heartbeats of the stores without changing their slow
+// trends.
+func (c *slowTrendTestCluster) heartbeat(storeIDs ...uint64) {
+	for _, storeID := range storeIDs {
+		c.PutStore(c.GetStore(storeID).Clone(core.SetLastHeartbeatTS(c.now)))
+	}
+}
+
+// advance moves the clock forward.
+func (c *slowTrendTestCluster) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// schedule runs the scheduler once if it's allowed, and returns the operators.
+func (c *slowTrendTestCluster) schedule(s Scheduler) []*operator.Operator {
+	if !s.IsScheduleAllowed(c) {
+		return nil
+	}
+	ops, _ := s.Schedule(c, false)
+	return ops
+}
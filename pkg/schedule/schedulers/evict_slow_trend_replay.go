@@ -0,0 +1,120 @@
+// Copyright 2026 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/core"
+	"github.com/tikv/pd/pkg/errs"
+	sche "github.com/tikv/pd/pkg/schedule/core"
+	"github.com/tikv/pd/pkg/schedule/operator"
+	"github.com/tikv/pd/pkg/storage"
+	"github.com/tikv/pd/pkg/utils/typeutil"
+)
+
+// replayScheduleDelay is the delay of running the scheduler after the stores
+// report the slow trends of a snapshot when replaying a timeline.
+const replayScheduleDelay = time.Millisecond
+
+// SlowTrendSnapshot is the slow trends reported by the stores at a moment of a
+// recorded timeline.
+type SlowTrendSnapshot struct {
+	TS         time.Time
+	SlowTrends map[uint64]*pdpb.SlowTrend
+}
+
+// SlowTrendDecision is a decision made by the evict-slow-trend scheduler, such
+// as capturing, evicting and recovering the store.
+type SlowTrendDecision struct {
+	TS      time.Time
+	StoreID uint64
+	Event   string
+}
+
+// ReplaySlowTrendTimeline feeds a recorded timeline of the slow trends through
+// the decision logic of the evict-slow-trend scheduler with the given config,
+// and returns the sequence of the decisions. It's used to validate the tuning
+// against the historical incidents.
+//
+// For each snapshot, the stores in the cluster are updated as if they had
+// reported the slow trends at its timestamp, then the scheduler runs once right
+// after it, with the clock driven by the timestamp. The timeline is rebased to
+// start from now, so that the stores are not regarded as disconnected, but the
+// decisions carry the original timestamps.
+func ReplaySlowTrendTimeline(cluster sche.SchedulerCluster, opController *operator.Controller, config []byte, timeline []SlowTrendSnapshot) ([]SlowTrendDecision, error) {
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	if len(config) > 0 {
+		if err := DecodeConfig(config, conf); err != nil {
+			return nil, err
+		}
+	}
+	if err := conf.validateLocked(); err != nil {
+		return nil, err
+	}
+	// Do not notify the external systems of the replayed decisions.
+	conf.WebhookURL = ""
+	conf.cluster = cluster.GetBasicCluster()
+	if len(timeline) == 0 {
+		return nil, nil
+	}
+	offset := time.Since(timeline[0].TS)
+	var now time.Time
+	conf.now = func() time.Time { return now.Add(offset + replayScheduleDelay) }
+	tracer := &replayEvictionTracer{now: &now}
+	s := newEvictSlowTrendScheduler(opController, conf, WithEvictSlowTrendTracer(tracer))
+	for _, snapshot := range timeline {
+		now = snapshot.TS
+		for storeID, slowTrend := range snapshot.SlowTrends {
+			store := cluster.GetStore(storeID)
+			if store == nil {
+				return nil, errs.ErrStoreNotFound.FastGenByArgs(storeID)
+			}
+			stats := typeutil.DeepClone(store.GetStoreStats(), core.StoreStatsFactory)
+			stats.SlowTrend = slowTrend
+			cluster.GetBasicCluster().PutStore(store.Clone(core.SetStoreStats(stats), core.SetLastHeartbeatTS(now.Add(offset))))
+		}
+		if s.IsScheduleAllowed(cluster) {
+			s.Schedule(cluster, false)
+		}
+	}
+	return tracer.decisions, nil
+}
+
+// replayEvictionTracer records the events of the eviction lifecycle as the
+// decisions, with the timestamps of the snapshots being replayed.
+type replayEvictionTracer struct {
+	now       *time.Time
+	decisions []SlowTrendDecision
+}
+
+// StartSpan implements EvictionTracer.
+func (t *replayEvictionTracer) StartSpan(_ string, storeID uint64) EvictionSpan {
+	return &replayEvictionSpan{tracer: t, storeID: storeID}
+}
+
+type replayEvictionSpan struct {
+	tracer  *replayEvictionTracer
+	storeID uint64
+}
+
+// AddEvent implements EvictionSpan.
+func (s *replayEvictionSpan) AddEvent(name string) {
+	s.tracer.decisions = append(s.tracer.decisions, SlowTrendDecision{TS: *s.tracer.now, StoreID: s.storeID, Event: name})
+}
+
+// End implements EvictionSpan.
+func (*replayEvictionSpan) End() {}
@@ -17,18 +17,38 @@ package schedulers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/tikv/pd/pkg/core"
 	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/pkg/schedule/config"
+	sche "github.com/tikv/pd/pkg/schedule/core"
+	"github.com/tikv/pd/pkg/schedule/hbstream"
 	"github.com/tikv/pd/pkg/schedule/operator"
 	"github.com/tikv/pd/pkg/storage"
 	"github.com/tikv/pd/pkg/utils/operatorutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type evictSlowTrendTestSuite struct {
@@ -81,6 +101,35 @@ func (suite *evictSlowTrendTestSuite) TearDownTest() {
 	suite.cancel()
 }
 
+func TestDurationSinceAsSecs(t *testing.T) {
+	re := require.New(t)
+	re.Zero(DurationSinceAsSecs(time.Time{}))
+	re.Zero(DurationSinceAsSecs(time.Now().Add(time.Hour)))
+	re.Zero(DurationSinceAsSecs(time.Now()))
+	re.Equal(uint64(60), DurationSinceAsSecs(time.Now().Add(-time.Minute)))
+}
+
+func (suite *evictSlowTrendTestSuite) setStoreSlowTrend(storeID uint64, slowTrend *pdpb.SlowTrend) {
+	storeInfo := suite.tc.GetStore(storeID)
+	newStoreInfo := storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = slowTrend
+	})
+	suite.tc.PutStore(newStoreInfo)
+}
+
+func (suite *evictSlowTrendTestSuite) updateStoresHeartbeat(storeIDs ...uint64) {
+	// The heartbeats follow the clock of the scheduler in case it's mocked.
+	now := suite.es.(*evictSlowTrendScheduler).conf.now()
+	for _, storeID := range storeIDs {
+		storeInfo := suite.tc.GetStore(storeID)
+		heartbeatTS := storeInfo.GetLastHeartbeatTS()
+		if heartbeatTS.Before(now) {
+			heartbeatTS = now
+		}
+		suite.tc.PutStore(storeInfo.Clone(core.SetLastHeartbeatTS(heartbeatTS.Add(time.Second))))
+	}
+}
+
 func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendBasicFuncs() {
 	re := suite.Require()
 	es2, ok := suite.es.(*evictSlowTrendScheduler)
@@ -93,99 +142,100 @@ func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendBasicFuncs() {
 	store := suite.tc.GetStore(1)
 	es2.conf.captureCandidate(store.GetID())
 	lastCapturedCandidate := es2.conf.lastCapturedCandidate()
-	re.Equal(*lastCapturedCandidate, es2.conf.evictCandidate)
+	re.Equal(*lastCapturedCandidate, es2.conf.EvictCandidate)
 	re.Zero(es2.conf.candidateCapturedSecs())
 	re.Zero(es2.conf.lastCandidateCapturedSecs())
 	re.False(es2.conf.readyForRecovery())
-	recoverTS := lastCapturedCandidate.recoverTS
-	re.True(recoverTS.After(lastCapturedCandidate.captureTS))
+	recoverTS := lastCapturedCandidate.RecoverTS
+	re.True(recoverTS.After(lastCapturedCandidate.CaptureTS))
 	// Pop captured store 1 and mark it has recovered.
 	time.Sleep(50 * time.Millisecond)
 	re.Equal(es2.conf.popCandidate(true), store.GetID())
-	re.Equal(slowCandidate{}, es2.conf.evictCandidate)
+	re.Equal(slowCandidate{}, es2.conf.EvictCandidate)
 	es2.conf.markCandidateRecovered()
 	lastCapturedCandidate = es2.conf.lastCapturedCandidate()
-	re.Positive(lastCapturedCandidate.recoverTS.Compare(recoverTS))
-	re.Equal(lastCapturedCandidate.storeID, store.GetID())
+	re.Positive(lastCapturedCandidate.RecoverTS.Compare(recoverTS))
+	re.Equal(lastCapturedCandidate.StoreID, store.GetID())
 
 	// Test capture another store 2
 	store = suite.tc.GetStore(2)
 	es2.conf.captureCandidate(store.GetID())
 	lastCapturedCandidate = es2.conf.lastCapturedCandidate()
-	re.Equal(uint64(1), lastCapturedCandidate.storeID)
+	re.Equal(uint64(1), lastCapturedCandidate.StoreID)
 	re.Equal(es2.conf.candidate(), store.GetID())
 	re.Zero(es2.conf.candidateCapturedSecs())
 
 	re.Equal(es2.conf.popCandidate(false), store.GetID())
-	re.Equal(uint64(1), lastCapturedCandidate.storeID)
+	re.Equal(uint64(1), lastCapturedCandidate.StoreID)
 }
 
-func (suite *evictSlowTrendTestSuite) TestEvictSlowTrend() {
-	re := suite.Require()
-	es2, ok := suite.es.(*evictSlowTrendScheduler)
-	re.True(ok)
-	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+func TestEvictSlowTrend(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).
+		addStore(1).
+		addStore(2, withTestLeaderCount(99)).
+		addStore(3, withTestLeaderCount(100)).
+		build()
+	defer c.close()
+	es := c.newScheduler()
+	bs := c.newBalanceLeaderScheduler()
 
 	// Set store-1 to slow status, generate evict candidate
-	re.Zero(es2.conf.evictedStore())
-	re.Zero(es2.conf.candidate())
-	storeInfo := suite.tc.GetStore(1)
-	newStoreInfo := storeInfo.Clone(func(store *core.StoreInfo) {
-		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
-			CauseValue:  5.0e8,
-			CauseRate:   1e7,
-			ResultValue: 3.0e3,
-			ResultRate:  -1e7,
-		}
-	})
-	suite.tc.PutStore(newStoreInfo)
-	re.True(suite.es.IsScheduleAllowed(suite.tc))
-	ops, _ := suite.es.Schedule(suite.tc, false)
-	re.Empty(ops)
-	re.Equal(uint64(1), es2.conf.candidate())
-	re.Zero(es2.conf.evictedStore())
+	re.Zero(es.conf.evictedStore())
+	re.Zero(es.conf.candidate())
+	c.report(1, slowSlowTrend())
+	re.True(es.IsScheduleAllowed(c))
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+	re.Zero(es.conf.evictedStore())
+	lastCapturedCandidate := es.conf.lastCapturedCandidate()
+	re.Equal(*lastCapturedCandidate, es.conf.EvictCandidate)
+	re.Zero(es.conf.candidateCapturedSecs())
+	re.Zero(es.conf.lastCandidateCapturedSecs())
+	re.False(es.conf.readyForRecovery())
+	captureTS, recoverTS := lastCapturedCandidate.CaptureTS, lastCapturedCandidate.RecoverTS
+	re.False(recoverTS.Before(captureTS))
 
 	// Update other stores' heartbeat-ts, do evicting
-	for storeID := uint64(2); storeID <= uint64(3); storeID++ {
-		storeInfo := suite.tc.GetStore(storeID)
-		newStoreInfo := storeInfo.Clone(
-			core.SetLastHeartbeatTS(storeInfo.GetLastHeartbeatTS().Add(time.Second)),
-		)
-		suite.tc.PutStore(newStoreInfo)
-	}
-	ops, _ = suite.es.Schedule(suite.tc, false)
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	ops := c.schedule(es)
+	re.Len(ops, 1)
 	operatorutil.CheckMultiTargetTransferLeader(re, ops[0], operator.OpLeader, 1, []uint64{2, 3})
 	re.Equal(EvictSlowTrendType, ops[0].Desc())
-	re.Zero(es2.conf.candidate())
-	re.Equal(uint64(1), es2.conf.evictedStore())
+	re.Zero(es.conf.candidate())
+	re.Equal(slowCandidate{}, es.conf.EvictCandidate)
+	re.Equal(uint64(1), es.conf.evictedStore())
+	re.Equal(uint64(1), es.conf.lastCapturedCandidate().StoreID)
+	re.Equal(captureTS, es.conf.lastCapturedCandidate().CaptureTS)
 	// Cannot balance leaders to store 1
-	ops, _ = suite.bs.Schedule(suite.tc, false)
-	re.Empty(ops)
+	re.Empty(c.schedule(bs))
 
-	// Set store-1 to normal status
-	newStoreInfo = storeInfo.Clone(func(store *core.StoreInfo) {
-		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
-			CauseValue:  5.0e6,
-			CauseRate:   0.0,
-			ResultValue: 5.0e3,
-			ResultRate:  0.0,
-		}
-	})
-	suite.tc.PutStore(newStoreInfo)
+	// Set store-1 to normal status, but it's not recovered before the recovery gap.
+	c.advance(time.Second)
+	c.report(1, normalSlowTrend())
+	c.schedule(es)
+	re.Equal(uint64(1), es.conf.evictedStore())
 	// Evict leader scheduler of store 1 should be removed, then leaders should be balanced from store-3 to store-1
-	ops, _ = suite.es.Schedule(suite.tc, false)
-	re.Empty(ops)
-	re.Zero(es2.conf.evictedStore())
-	ops, _ = suite.bs.Schedule(suite.tc, false)
+	c.advance(time.Duration(es.conf.RecoveryDurationGap) * time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+	lastCapturedCandidate = es.conf.lastCapturedCandidate()
+	re.Equal(uint64(1), lastCapturedCandidate.StoreID)
+	re.Positive(lastCapturedCandidate.RecoverTS.Compare(recoverTS))
+	re.True(lastCapturedCandidate.RecoverTS.After(lastCapturedCandidate.CaptureTS))
+	ops = c.schedule(bs)
+	re.NotEmpty(ops)
 	operatorutil.CheckTransferLeader(re, ops[0], operator.OpLeader, 3, 1)
 
 	// no slow store need to evict.
-	ops, _ = suite.es.Schedule(suite.tc, false)
-	re.Empty(ops)
-	re.Zero(es2.conf.evictedStore())
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
 
 	// check the value from storage.
-	sches, vs, err := es2.conf.storage.LoadAllSchedulerConfigs()
+	sches, vs, err := es.conf.storage.LoadAllSchedulerConfigs()
 	re.NoError(err)
 	valueStr := ""
 	for id, sche := range sches {
@@ -197,74 +247,4267 @@ func (suite *evictSlowTrendTestSuite) TestEvictSlowTrend() {
 	var persistValue evictSlowTrendSchedulerConfig
 	err = json.Unmarshal([]byte(valueStr), &persistValue)
 	re.NoError(err)
-	re.Equal(es2.conf.EvictedStores, persistValue.EvictedStores)
+	re.Equal(es.conf.EvictedStores, persistValue.EvictedStores)
 	re.Zero(persistValue.evictedStore())
-	re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+
+	// Capture another store 2, the last captured candidate is kept.
+	c.advance(time.Second)
+	c.report(2, slowSlowTrend())
+	re.True(es.IsScheduleAllowed(c))
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(2), es.conf.candidate())
+	re.Zero(es.conf.candidateCapturedSecs())
+	re.Equal(uint64(1), es.conf.lastCapturedCandidate().StoreID)
+	re.Equal(uint64(2), es.conf.popCandidate(false))
+	re.Equal(uint64(1), es.conf.lastCapturedCandidate().StoreID)
 }
 
-func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendV2() {
-	re := suite.Require()
-	es2, ok := suite.es.(*evictSlowTrendScheduler)
-	re.True(ok)
-	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+func TestEvictSlowTrendV2(t *testing.T) {
+	re := require.New(t)
 	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2"))
+	}()
+	c := newSlowTrendTestClusterBuilder(re).
+		addStore(1).
+		addStore(2, withTestLeaderCount(99)).
+		addStore(3, withTestLeaderCount(100)).
+		build()
+	defer c.close()
+	es := c.newScheduler()
 
-	re.Zero(es2.conf.evictedStore())
-	re.Zero(es2.conf.candidate())
+	re.Zero(es.conf.evictedStore())
+	re.Zero(es.conf.candidate())
 	// Set store-1 to slow status, generate slow candidate but under faster limit
-	storeInfo := suite.tc.GetStore(1)
-	newStoreInfo := storeInfo.Clone(func(store *core.StoreInfo) {
-		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
-			CauseValue:  5.0e6 + 100,
-			CauseRate:   1e7,
-			ResultValue: 3.0e3,
-			ResultRate:  -1e7,
-		}
+	c.report(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e6 + 100,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
 	})
-	suite.tc.PutStore(newStoreInfo)
-	re.True(suite.es.IsScheduleAllowed(suite.tc))
-	ops, _ := suite.es.Schedule(suite.tc, false)
-	re.Empty(ops)
-	re.Zero(es2.conf.evictedStore())
-	re.Equal(uint64(1), es2.conf.candidate())
-	re.Zero(es2.conf.lastCandidateCapturedSecs())
+	re.True(es.IsScheduleAllowed(c))
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+	re.Equal(uint64(1), es.conf.candidate())
+	re.Zero(es.conf.lastCandidateCapturedSecs())
+	re.Equal(uint64(1), es.conf.lastCapturedCandidate().StoreID)
+	re.False(es.conf.lastCapturedCandidate().RecoverTS.Before(es.conf.lastCapturedCandidate().CaptureTS))
 	// Rescheduling to make it filtered by the related faster judgement.
-	ops, _ = suite.es.Schedule(suite.tc, false)
-	re.Empty(ops)
-	re.Zero(es2.conf.evictedStore())
-	re.Zero(es2.conf.candidate())
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+	re.Zero(es.conf.candidate())
 
 	// Set store-1 to slow status as network-io delays
-	storeInfo = suite.tc.GetStore(1)
-	newStoreInfo = storeInfo.Clone(func(store *core.StoreInfo) {
-		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
-			CauseValue:  5.0e6,
-			CauseRate:   1e7,
-			ResultValue: 0,
-			ResultRate:  0,
+	c.report(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   1e7,
+		ResultValue: 0,
+		ResultRate:  0,
+	})
+	re.True(es.IsScheduleAllowed(c))
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+	re.Zero(es.conf.lastCandidateCapturedSecs())
+}
+
+func TestEvictSlowTrendNotRecoveredWithStaleHeartbeat(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).
+		addStore(1, withTestSlowTrend(slowSlowTrend())).
+		addStore(2).
+		addStore(3).
+		addStore(4, withTestPreparing(), withTestHeartbeatAge(time.Minute)).
+		build()
+	defer c.close()
+	es := c.newScheduler()
+
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+	c.advance(time.Second)
+	c.heartbeat(2, 3, 4)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+
+	// Store-1 does not report anything since evicted, so it's not recovered even
+	// if its last slow trend is normal.
+	c.PutStore(c.GetStore(1).Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = normalSlowTrend()
+	}))
+	c.advance(time.Duration(es.conf.RecoveryDurationGap) * time.Second)
+	c.heartbeat(2, 3, 4)
+	c.schedule(es)
+	re.Equal(uint64(1), es.conf.evictedStore())
+
+	// It's recovered once it reports the normal slow trend.
+	c.report(1, normalSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+}
+
+// configlessCluster is a cluster whose store config and scheduler config are
+// not available yet, e.g., in the early startup.
+type configlessCluster struct {
+	*mockcluster.Cluster
+}
+
+func (*configlessCluster) GetStoreConfig() config.StoreConfigProvider {
+	return nil
+}
+
+func (*configlessCluster) GetSchedulerConfig() config.SchedulerConfigProvider {
+	return nil
+}
+
+func TestEvictSlowTrendWithoutClusterConfig(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	cluster := &configlessCluster{Cluster: c.Cluster}
+	re.False(isRaftKV2Cluster(cluster))
+	re.Equal(defaultAffectedStoreRatioThreshold, affectedStoreRatioThreshold(cluster))
+
+	c.report(1, slowSlowTrend())
+	var store *core.StoreInfo
+	re.NotPanics(func() {
+		store = chooseEvictCandidate(cluster, cluster.GetStores(), es.conf, nil)
+	})
+	re.NotNil(store)
+	re.Equal(uint64(1), store.GetID())
+}
+
+func TestEvictSlowTrendBatchStatus(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).addStore(4).build()
+	defer c.close()
+	es := c.newScheduler()
+
+	// Store-1 is evicted, store-2 is the candidate and the others are idle.
+	c.report(1, slowSlowTrend())
+	c.schedule(es)
+	c.advance(time.Second)
+	c.heartbeat(2, 3, 4)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+	es.conf.captureCandidate(2)
+	c.advance(time.Minute)
+
+	storeIDs := []uint64{1, 2, 3, 4, 99}
+	statuses := es.BatchStatus(storeIDs)
+	re.Len(statuses, len(storeIDs))
+	for _, storeID := range storeIDs {
+		re.Equal(es.StoreStatus(storeID), statuses[storeID])
+	}
+	re.Equal(slowTrendStoreStatusEvicted, statuses[1].State)
+	re.Greater(statuses[1].ElapsedSecs, uint64(0))
+	re.Greater(statuses[1].RecoveryProgress, 0.0)
+	re.LessOrEqual(statuses[1].RecoveryProgress, 1.0)
+	re.Equal(slowTrendStoreStatusCandidate, statuses[2].State)
+	re.Equal(uint64(60), statuses[2].ElapsedSecs)
+	re.Zero(statuses[2].RecoveryProgress)
+	for _, storeID := range []uint64{3, 4, 99} {
+		re.Equal(SlowTrendStoreStatus{State: slowTrendStoreStatusIdle}, statuses[storeID])
+	}
+	re.Empty(es.BatchStatus(nil))
+}
+
+func TestEvictSlowTrendBelowMinStores(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).addStore(4).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.MinStoresForEvicting = 4
+	stopped := storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_below_min_stores")
+	before := testutil.ToFloat64(stopped)
+
+	c.report(1, slowSlowTrend())
+	c.schedule(es)
+	c.advance(time.Second)
+	c.heartbeat(2, 3, 4)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+	c.advance(time.Second)
+	c.report(1, slowSlowTrend())
+	c.heartbeat(2, 3, 4)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+
+	// Store-4 is removed during the eviction, which is halted though store-1 is
+	// still slow.
+	c.PutStore(c.GetStore(4).Clone(core.SetStoreState(metapb.StoreState_Tombstone)))
+	c.advance(time.Second)
+	c.report(1, slowSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+	re.False(c.GetStore(1).IsEvictedAsSlowTrend())
+	re.Equal(before+1, testutil.ToFloat64(stopped))
+
+	// And it's not captured again.
+	c.advance(time.Second)
+	c.report(1, slowSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.candidate())
+	re.Zero(es.conf.evictedStore())
+
+	es.conf.MinStoresForEvicting = 2
+	re.Error(es.conf.validateLocked())
+}
+
+func TestEvictSlowTrendPriorityKeyRanges(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	for regionID := uint64(11); regionID <= 14; regionID++ {
+		c.AddLeaderRegion(regionID, 1, 2, 3)
+	}
+	es := c.newScheduler()
+	// The leader of region 13 is evicted before the others.
+	region := c.GetRegion(13)
+	es.conf.PriorityKeyRanges = []core.KeyRange{core.NewKeyRange(string(region.GetStartKey()), string(region.GetEndKey()))}
+	re.NoError(es.prepareEvictLeader(c, 1))
+
+	checkEvictedRegions := func(check func(regionID uint64)) {
+		for i := 0; i < 10; i++ {
+			ops := es.scheduleEvictLeader(c)
+			re.NotEmpty(ops)
+			for _, op := range ops {
+				check(op.RegionID())
+			}
 		}
+	}
+	checkEvictedRegions(func(regionID uint64) {
+		re.Equal(uint64(13), regionID)
+	})
+
+	// The others are evicted once the leader in the priority range is moved.
+	c.AddLeaderRegion(13, 2, 1, 3)
+	checkEvictedRegions(func(regionID uint64) {
+		re.NotEqual(uint64(13), regionID)
 	})
-	suite.tc.PutStore(newStoreInfo)
-	re.True(suite.es.IsScheduleAllowed(suite.tc))
-	ops, _ = suite.es.Schedule(suite.tc, false)
-	re.Empty(ops)
-	re.Zero(es2.conf.evictedStore())
-	re.Zero(es2.conf.lastCandidateCapturedSecs())
 
-	re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2"))
-	re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	// The priority ranges out of the key ranges to evict take no effect.
+	es.conf.KeyRanges = []core.KeyRange{core.NewKeyRange(string(c.GetRegion(11).GetStartKey()), string(c.GetRegion(11).GetEndKey()))}
+	es.conf.PriorityKeyRanges = []core.KeyRange{core.NewKeyRange(string(c.GetRegion(12).GetStartKey()), string(c.GetRegion(12).GetEndKey()))}
+	checkEvictedRegions(func(regionID uint64) {
+		re.Equal(uint64(11), regionID)
+	})
 }
 
-func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendPrepare() {
-	re := suite.Require()
-	es2, ok := suite.es.(*evictSlowTrendScheduler)
+func TestEvictSlowTrendSkippedStores(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).
+		addStore(1, withTestSlowTrend(slowSlowTrend())).
+		addStore(2).addStore(3).addStore(4).addStore(5).addStore(6).
+		addStore(7, withTestLabel(core.EngineKey, core.EngineTiFlash)).
+		addStore(8, withTestSlowTrend(nil)).
+		addStore(9, withTestSlowTrend(nil)).
+		build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.EngineFilter = core.EngineTiKV
+	es.conf.AffectedStoreRatioThreshold = 0.1
+	// The median comparison is not diluted by the skipped stores.
+	es.conf.ComparisonMethod = slowTrendComparisonMedian
+	c.PutStore(c.GetStore(2).Clone(core.SetStoreState(metapb.StoreState_Tombstone)))
+	c.PutStore(c.GetStore(3).Clone(core.SetStoreState(metapb.StoreState_Offline, false)))
+	c.PutStore(c.GetStore(4).Clone(core.SetStoreState(metapb.StoreState_Offline, false)))
+
+	skipped := func(reason string) float64 {
+		return testutil.ToFloat64(storeSlowTrendSkippedStoresGauge.WithLabelValues(reason))
+	}
+	re.NotNil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+	re.Equal(1.0, skipped(skipReasonRemoved))
+	re.Equal(2.0, skipped(skipReasonNotServing))
+	re.Equal(1.0, skipped(skipReasonEngineFiltered))
+	re.Equal(2.0, skipped(skipReasonNoTrendData))
+	re.Zero(skipped(skipReasonWarmingUp))
+
+	// The slow store is skipped since it just started.
+	c.PutStore(c.GetStore(1).Clone(core.SetStoreStartTime(c.now.Add(-time.Minute).Unix())))
+	es.conf.WarmupDuration = uint64(time.Hour.Seconds())
+	re.Nil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+	re.Equal(1.0, skipped(skipReasonWarmingUp))
+
+	// The gauges are refreshed in each scan.
+	es.conf.EngineFilter = ""
+	es.conf.WarmupDuration = 0
+	re.NotNil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+	re.Zero(skipped(skipReasonEngineFiltered))
+	re.Zero(skipped(skipReasonWarmingUp))
+	re.Equal(2.0, skipped(skipReasonNotServing))
+}
+
+func TestEvictSlowTrendProfile(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+
+	expected := map[string]slowTrendProfile{
+		slowTrendProfileConservative: {AffectedStoreRatioThreshold: 0.5, RecoveryQuorumRatio: 0.34, RecoveryDurationGap: 300, ComparisonEpsilon: 1e-3},
+		slowTrendProfileBalanced:     {AffectedStoreRatioThreshold: 0.3, RecoveryQuorumRatio: 0, RecoveryDurationGap: 600, ComparisonEpsilon: 1e-9},
+		slowTrendProfileAggressive:   {AffectedStoreRatioThreshold: 0.1, RecoveryQuorumRatio: 0.8, RecoveryDurationGap: 1800, ComparisonEpsilon: 1e-12},
+	}
+	for name, profile := range expected {
+		code, _ := es.conf.update([]byte(fmt.Sprintf(`{"profile":%q}`, name)))
+		re.Equal(http.StatusOK, code, name)
+		cfg := es.conf.Clone()
+		re.Equal(name, cfg.Profile)
+		re.Equal(profile, slowTrendProfile{
+			AffectedStoreRatioThreshold: cfg.AffectedStoreRatioThreshold,
+			RecoveryQuorumRatio:         cfg.RecoveryQuorumRatio,
+			RecoveryDurationGap:         cfg.RecoveryDurationGap,
+			ComparisonEpsilon:           cfg.ComparisonEpsilon,
+		}, name)
+	}
+
+	// The items set along with the profile take precedence.
+	code, _ := es.conf.update([]byte(`{"profile":"aggressive","recovery-duration":100}`))
+	re.Equal(http.StatusOK, code)
+	cfg := es.conf.Clone()
+	re.Equal(uint64(100), cfg.RecoveryDurationGap)
+	re.Equal(0.1, cfg.AffectedStoreRatioThreshold)
+	// The items set later are not reset by the profile.
+	code, _ = es.conf.update([]byte(`{"comparison-epsilon":0.01}`))
+	re.Equal(http.StatusOK, code)
+	cfg = es.conf.Clone()
+	re.Equal(0.01, cfg.ComparisonEpsilon)
+	re.Equal(uint64(100), cfg.RecoveryDurationGap)
+
+	code, _ = es.conf.update([]byte(`{"profile":"unknown"}`))
+	re.Equal(http.StatusBadRequest, code)
+	re.Equal(slowTrendProfileAggressive, es.conf.Clone().Profile)
+
+	// The affected store ratio overrides the one of the scheduler config.
+	es.conf.AffectedStoreRatioThreshold = 0.9
+	c.report(1, slowSlowTrend())
+	re.Nil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+	es.conf.AffectedStoreRatioThreshold = 0.1
+	re.NotNil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+}
+
+func TestEvictSlowTrendRequireBecomeFastEvent(t *testing.T) {
+	re := require.New(t)
+	for _, required := range []bool{false, true} {
+		c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+		es := c.newScheduler()
+		es.conf.RequireBecomeFastEvent = required
+		noEvent := storeSlowTrendActionStatusGauge.WithLabelValues("recover", "reject_no_fast_event")
+		before := testutil.ToFloat64(noEvent)
+
+		c.report(1, slowSlowTrend())
+		c.schedule(es)
+		c.advance(time.Second)
+		c.heartbeat(2, 3)
+		re.NotEmpty(c.schedule(es))
+		re.Equal(uint64(1), es.conf.evictedStore())
+
+		// Store-1 looks as fast as others, but its own trend is flat.
+		for i := 0; i < 2; i++ {
+			c.advance(time.Hour)
+			c.report(1, normalSlowTrend())
+			c.heartbeat(2, 3)
+			c.schedule(es)
+		}
+		if !required {
+			re.Zero(es.conf.evictedStore())
+			re.Equal(before, testutil.ToFloat64(noEvent))
+			c.close()
+			continue
+		}
+		re.Equal(uint64(1), es.conf.evictedStore())
+		re.Less(before, testutil.ToFloat64(noEvent))
+
+		// Store-1 reports its cause is reversing.
+		becomeFast := normalSlowTrend()
+		becomeFast.CauseRate = -1e7
+		for i := 0; i < 2; i++ {
+			c.advance(time.Hour)
+			c.report(1, becomeFast)
+			c.heartbeat(2, 3)
+			c.schedule(es)
+		}
+		re.Zero(es.conf.evictedStore())
+		c.close()
+	}
+}
+
+func TestEvictSlowTrendReadmission(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.ReadmissionStages = []float64{0.25, 0.5}
+	es.conf.ReadmissionStageDuration = 60
+	re.NoError(es.conf.validateLocked())
+	re.Equal(1.0, c.GetStore(1).GetLeaderWeight())
+
+	c.report(1, slowSlowTrend())
+	c.schedule(es)
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+	c.advance(time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	c.schedule(es)
+	c.advance(time.Hour)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	c.schedule(es)
+	re.Zero(es.conf.evictedStore())
+
+	// The leader weight of the recovered store is ramped up stage by stage.
+	re.Equal(0.25, c.GetStore(1).GetLeaderWeight())
+	c.advance(30 * time.Second)
+	c.schedule(es)
+	re.Equal(0.25, c.GetStore(1).GetLeaderWeight())
+
+	// The re-admission goes on after the leader changes.
+	s, err := CreateScheduler(EvictSlowTrendType, c.oc, es.conf.storage, ConfigSliceDecoder(EvictSlowTrendType, []string{}))
+	re.NoError(err)
+	re.NoError(s.ReloadConfig())
+	es, ok := s.(*evictSlowTrendScheduler)
 	re.True(ok)
-	re.Zero(es2.conf.evictedStore())
-	// prepare with no evict store.
-	suite.es.PrepareConfig(suite.tc)
+	es.conf.now = func() time.Time { return c.now }
+	r := es.conf.readmission()
+	re.Equal(uint64(1), r.StoreID)
+	re.Equal(1.0, r.OriginalWeight)
+	re.Equal(0.25, r.Weight)
+	re.Zero(r.Stage)
+	c.advance(30 * time.Second)
+	c.schedule(es)
+	re.Equal(0.5, c.GetStore(1).GetLeaderWeight())
+	re.Equal(1, es.conf.readmission().Stage)
+	c.advance(60 * time.Second)
+	c.schedule(es)
+	re.Equal(1.0, c.GetStore(1).GetLeaderWeight())
+	re.Zero(es.conf.readmission())
 
-	es2.conf.setStoreAndPersist(1)
-	re.Equal(uint64(1), es2.conf.evictedStore())
-	// prepare with evict store.
-	suite.es.PrepareConfig(suite.tc)
+	// The weight changed by others during the re-admission is not overwritten.
+	re.True(es.conf.readmissionEnabled())
+	es.startReadmission(c, 1)
+	re.Equal(0.25, c.GetStore(1).GetLeaderWeight())
+	re.NoError(c.SetStoreWeight(1, 3, 1))
+	c.advance(60 * time.Second)
+	c.schedule(es)
+	re.Equal(3.0, c.GetStore(1).GetLeaderWeight())
+	re.Zero(es.conf.readmission())
+
+	// The store is fully restored at once if the weight can't be persisted by
+	// the cluster, e.g., in the scheduling service.
+	es.startReadmission(&weightUnawareCluster{SchedulerCluster: c}, 1)
+	re.Equal(3.0, c.GetStore(1).GetLeaderWeight())
+	re.Zero(es.conf.readmission())
+
+	// The stages without the duration take no effect.
+	es.conf.ReadmissionStageDuration = 0
+	re.Error(es.conf.validateLocked())
+	es.conf.ReadmissionStageDuration = 60
+	es.conf.ReadmissionStages = []float64{0.5, 0.25}
+	re.Error(es.conf.validateLocked())
+}
+
+func TestEvictSlowTrendHistory(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	listHistory := func() []slowTrendHistoryEntry {
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1/history", http.NoBody)
+		re.NoError(err)
+		resp := httptest.NewRecorder()
+		es.ServeHTTP(resp, req)
+		re.Equal(http.StatusOK, resp.Code)
+		var history []slowTrendHistoryEntry
+		re.NoError(json.Unmarshal(resp.Body.Bytes(), &history))
+		return history
+	}
+	re.Empty(listHistory())
+
+	captureTrend := slowSlowTrend()
+	c.report(1, captureTrend)
+	c.schedule(es)
+	c.advance(time.Second)
+	evictTrend := slowSlowTrend()
+	evictTrend.CauseValue *= 2
+	c.report(1, evictTrend)
+	c.heartbeat(2, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+	history := listHistory()
+	re.Len(history, 1)
+	re.Equal(uint64(1), history[0].StoreID)
+	re.Equal(captureTrend.CauseValue, history[0].Capture.CauseValue)
+	re.Equal(evictTrend.CauseValue, history[0].Evict.CauseValue)
+	re.Nil(history[0].Recover)
+	re.Empty(history[0].Reason)
+
+	c.advance(time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	c.schedule(es)
+	c.advance(time.Hour)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	c.schedule(es)
+	re.Zero(es.conf.evictedStore())
+	history = listHistory()
+	re.Len(history, 1)
+	entry := history[0]
+	re.Equal(string(evictCleanupReasonRecovered), entry.Reason)
+	re.NotNil(entry.Recover)
+	re.Equal(normalSlowTrend().CauseValue, entry.Recover.CauseValue)
+	re.NotEqual(*entry.Capture, *entry.Recover)
+	re.True(entry.Capture.TS.Before(entry.Evict.TS))
+	re.True(entry.Evict.TS.Before(entry.Recover.TS))
+}
+
+func TestEvictSlowTrendReconcileInflightOperators(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	for regionID := uint64(11); regionID <= 16; regionID++ {
+		c.AddLeaderRegion(regionID, 1, 2, 3)
+	}
+
+	// The previous leader has issued the eviction operators before the failover.
+	oldLeader := c.newScheduler()
+	c.report(1, slowSlowTrend())
+	c.schedule(oldLeader)
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	inflight := c.schedule(oldLeader)
+	re.NotEmpty(inflight)
+	re.Equal(len(inflight), c.oc.AddWaitingOperator(inflight...))
+	inflightRegions := make(map[uint64]struct{})
+	for _, op := range inflight {
+		inflightRegions[op.RegionID()] = struct{}{}
+	}
+
+	// The new leader loads the evicted store and reconciles on prepare.
+	newLeader := c.newScheduler()
+	re.NoError(newLeader.conf.setStoreAndPersist(1))
+	re.NoError(newLeader.PrepareConfig(c))
+	for i := 0; i < 10; i++ {
+		ops := c.schedule(newLeader)
+		re.NotEmpty(ops)
+		for _, op := range ops {
+			re.NotContains(inflightRegions, op.RegionID())
+		}
+	}
+
+	// The region is evicted again once its operator finishes.
+	op := c.oc.GetOperator(inflight[0].RegionID())
+	re.NotNil(op)
+	re.True(c.oc.RemoveOperator(op))
+	re.False(newLeader.conf.isInflightRegion(op.RegionID()))
+}
+
+func TestEvictSlowTrendForceCandidate(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.SetTraceEnabled(true)
+	// Store-2 is slow, but it's only alerted rather than selected.
+	es.conf.EnableDiskSlowEvict = false
+	c.report(2, slowSlowTrend())
+	re.Nil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+
+	fname := "github.com/tikv/pd/pkg/schedule/schedulers/forceSlowTrendCandidate"
+	re.NoError(failpoint.Enable(fname, "return(2)"))
+	re.Empty(c.schedule(es))
+	re.NoError(failpoint.Disable(fname))
+	re.Equal(uint64(2), es.conf.candidate())
+	re.Contains(es.LastTrace().Branches, "candidate_forced")
+
+	// The forced candidate is still confirmed and evicted as usual.
+	c.advance(time.Second)
+	c.report(2, slowSlowTrend())
+	c.heartbeat(1, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(2), es.conf.evictedStore())
+	re.True(c.GetStore(2).IsEvictedAsSlowTrend())
+
+	// And it's recovered as usual.
+	c.advance(time.Second)
+	c.report(2, normalSlowTrend())
+	c.heartbeat(1, 3)
+	c.schedule(es)
+	re.Equal(uint64(2), es.conf.evictedStore())
+	c.advance(time.Hour)
+	c.report(2, normalSlowTrend())
+	c.heartbeat(1, 3)
+	c.schedule(es)
+	re.Zero(es.conf.evictedStore())
+	re.False(c.GetStore(2).IsEvictedAsSlowTrend())
+}
+
+func TestEvictSlowTrendAffectedRatioOverEligible(t *testing.T) {
+	re := require.New(t)
+	b := newSlowTrendTestClusterBuilder(re)
+	for id := uint64(1); id <= 10; id++ {
+		b.addStore(id)
+	}
+	c := b.build()
+	defer c.close()
+	es := c.newScheduler()
+	// The median comparison is not diluted by the non-serving stores.
+	es.conf.ComparisonMethod = slowTrendComparisonMedian
+	// Only store-1 to store-4 are serving, the others are being decommissioned.
+	for id := uint64(5); id <= 10; id++ {
+		c.PutStore(c.GetStore(id).Clone(core.SetStoreState(metapb.StoreState_Offline, false)))
+	}
+	c.report(1, slowSlowTrend())
+
+	// The store affected by store-1 is diluted by the non-serving stores.
+	re.Nil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+	re.Equal(3.0, testutil.ToFloat64(storeSlowTrendMiscGauge.WithLabelValues("store", "affected_threshold")))
+	es.conf.AffectedRatioOverEligible = true
+	store := chooseEvictCandidate(c, c.GetStores(), es.conf, nil)
+	re.NotNil(store)
+	re.Equal(uint64(1), store.GetID())
+	re.Equal(1.0, testutil.ToFloat64(storeSlowTrendMiscGauge.WithLabelValues("store", "affected_threshold")))
+}
+
+func TestEvictSlowTrendOfflineEvictedStore(t *testing.T) {
+	re := require.New(t)
+	for _, policy := range []string{offlineEvictedStorePolicyKeep, offlineEvictedStorePolicyStop} {
+		c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).addStore(4).build()
+		es := c.newScheduler()
+		es.conf.OfflineEvictedStorePolicy = policy
+		stopped := storeSlowTrendActionStatusGauge.WithLabelValues("evict", "stop_offline")
+		before := testutil.ToFloat64(stopped)
+
+		c.report(1, slowSlowTrend())
+		c.schedule(es)
+		c.advance(time.Second)
+		c.heartbeat(2, 3, 4)
+		re.NotEmpty(c.schedule(es))
+		re.Equal(uint64(1), es.conf.evictedStore())
+		re.True(c.GetStore(1).IsEvictedAsSlowTrend())
+
+		// Store-1 is being decommissioned while it's still slow.
+		c.advance(time.Second)
+		c.PutStore(c.GetStore(1).Clone(core.SetStoreState(metapb.StoreState_Offline, false)))
+		c.report(1, slowSlowTrend())
+		c.heartbeat(2, 3, 4)
+		re.True(c.GetStore(1).IsRemoving())
+		ops := c.schedule(es)
+		if policy == offlineEvictedStorePolicyKeep {
+			re.Equal(uint64(1), es.conf.evictedStore(), policy)
+			re.True(c.GetStore(1).IsEvictedAsSlowTrend(), policy)
+			re.Equal(before, testutil.ToFloat64(stopped), policy)
+		} else {
+			re.Empty(ops, policy)
+			re.Zero(es.conf.evictedStore(), policy)
+			re.Empty(es.conf.getStores(), policy)
+			re.False(c.GetStore(1).IsEvictedAsSlowTrend(), policy)
+			re.Equal(before+1, testutil.ToFloat64(stopped), policy)
+		}
+		c.close()
+	}
+}
+
+func TestEvictSlowTrendSymmetricRecovery(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.SymmetricRecovery = true
+	rejected := storeSlowTrendActionStatusGauge.WithLabelValues("recover", "reject_still_slower")
+	before := testutil.ToFloat64(rejected)
+	code, _ := es.conf.update([]byte(`{"recovery-quorum-ratio":1.5}`))
+	re.Equal(http.StatusBadRequest, code)
+
+	c.report(1, slowSlowTrend())
+	c.schedule(es)
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+	es.conf.RecoveryDurationGap = 0
+
+	// Store-1 is within the tolerance of the recovery quorum, but it's still
+	// slower than others by the capture standard.
+	marginal := normalSlowTrend()
+	marginal.CauseValue *= 1.05
+	c.advance(time.Second)
+	c.report(1, marginal)
+	c.heartbeat(2, 3)
+	re.True(checkStoreFasterThanOthers(c.GetStores(), c.GetStore(1), false, alterEpsilon))
+	re.True(checkStoreSlowerThanOthers(c.GetStores(), c.GetStore(1), false, slowTrendComparisonPairwise, alterEpsilon))
+	c.schedule(es)
+	re.Equal(uint64(1), es.conf.evictedStore())
+	re.Equal(before+1, testutil.ToFloat64(rejected))
+
+	c.advance(time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+}
+
+func TestEvictSlowTrendDecisionTrace(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	// The trace is disabled by default.
+	c.schedule(es)
+	re.Nil(es.LastTrace())
+
+	es.SetTraceEnabled(true)
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	trace := es.LastTrace()
+	re.NotNil(trace)
+	re.Equal([]string{"select_candidate", "candidate_captured", "candidate_wait_heartbeats"}, trace.Branches)
+	re.Equal("candidate_wait_heartbeats", trace.Decision)
+	// Both pre-capturing and confirming the candidate count as "add".
+	re.Equal(2.0, trace.Counters["candidate/add"])
+	re.Equal(1.0, trace.Counters["candidate/captured"])
+	re.Equal(1.0, trace.Counters["candidate/wait"])
+	re.NotContains(trace.Counters, "candidate/none")
+	re.Zero(trace.Operators)
+
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	ops := c.schedule(es)
+	re.NotEmpty(ops)
+	trace = es.LastTrace()
+	re.Equal([]string{"candidate_pending", "evict_start"}, trace.Branches)
+	re.Equal("evict_start", trace.Decision)
+	re.Equal(1.0, trace.Counters["evict/start"])
+	re.Equal(len(ops), trace.Operators)
+
+	es.SetTraceEnabled(false)
+	c.schedule(es)
+	re.Nil(es.LastTrace())
+}
+
+func TestEvictSlowTrendRecoveryFastScans(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.RecoveryFastScans = 3
+	re.Greater(es.conf.RecoveryDurationGap, uint64(60))
+
+	c.report(1, slowSlowTrend())
+	c.schedule(es)
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+
+	// A slow scan resets the count.
+	c.advance(time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	c.schedule(es)
+	c.advance(time.Second)
+	c.report(1, slowSlowTrend())
+	c.heartbeat(2, 3)
+	c.schedule(es)
+	re.Zero(es.conf.recoveryFastTicks)
+
+	// The store is recovered after 3 consecutive good scans, far before the
+	// recovery duration gap.
+	for i := 0; i < 2; i++ {
+		c.advance(time.Second)
+		c.report(1, normalSlowTrend())
+		c.heartbeat(2, 3)
+		c.schedule(es)
+		re.Equal(uint64(1), es.conf.evictedStore())
+	}
+	c.advance(time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+}
+
+func TestEvictSlowTrendSettlePeriod(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.RecoveryDurationGap = 0
+	es.conf.EvictSettlePeriod = 60
+	settling := storeSlowTrendActionStatusGauge.WithLabelValues("evict", "continue_settling")
+	before := testutil.ToFloat64(settling)
+
+	c.report(1, slowSlowTrend())
+	c.schedule(es)
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+
+	// The comparison flips at once, but the recovery is not evaluated within
+	// the settle period.
+	for i := 0; i < 3; i++ {
+		c.advance(19 * time.Second)
+		c.report(1, normalSlowTrend())
+		c.heartbeat(2, 3)
+		c.schedule(es)
+		re.Equal(uint64(1), es.conf.evictedStore())
+		re.Zero(es.conf.recoveryFastTicks)
+	}
+	re.Equal(before+3, testutil.ToFloat64(settling))
+
+	c.advance(3 * time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+	re.Equal(before+3, testutil.ToFloat64(settling))
+}
+
+func TestEvictSlowTrendReferenceGroup(t *testing.T) {
+	re := require.New(t)
+	// The stores of the "storage" role are slow by design since they are loaded
+	// intentionally.
+	loaded := &pdpb.SlowTrend{CauseValue: 5.0e8, ResultValue: 5.0e3}
+	c := newSlowTrendTestClusterBuilder(re).
+		addStore(1, withTestLabel("role", "compute")).
+		addStore(2, withTestLabel("role", "compute")).
+		addStore(3, withTestLabel("role", "compute")).
+		addStore(4, withTestLabel("role", "storage"), withTestSlowTrend(loaded)).
+		addStore(5, withTestLabel("role", "storage"), withTestSlowTrend(loaded)).
+		addStore(6, withTestLabel("role", "storage"), withTestSlowTrend(loaded)).
+		build()
+	defer c.close()
+	es := c.newScheduler()
+	c.report(1, slowSlowTrend())
+
+	// Store-1 is not slower than most of the stores in the whole cluster.
+	re.Nil(chooseEvictCandidate(c, c.GetStores(), es.conf, nil))
+	// But it's slower than its peers of the same role.
+	es.conf.ReferenceGroupLabel = "role"
+	store := chooseEvictCandidate(c, c.GetStores(), es.conf, nil)
+	re.NotNil(store)
+	re.Equal(uint64(1), store.GetID())
+	// The candidate is recovered only if it's as fast as its peers.
+	re.False(es.isCandidateRecovered(c.GetStores(), c.GetStore(1)))
+	c.report(1, normalSlowTrend())
+	re.True(es.isCandidateRecovered(c.GetStores(), c.GetStore(1)))
+}
+
+func TestEvictSlowTrendAutoDisableAfterIdle(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.AutoDisableAfterIdle = 600
+	var removed []string
+	es.conf.removeSchedulerCb = func(name string) error {
+		removed = append(removed, name)
+		return nil
+	}
+
+	// The idle time is counted from the first tick.
+	re.Empty(c.schedule(es))
+	c.advance(598 * time.Second)
+	c.heartbeat(1, 2, 3)
+	re.Empty(c.schedule(es))
+	re.Empty(removed)
+
+	// A capture resets the idle time.
+	c.advance(time.Second)
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+	re.Equal(c.now, es.conf.LastActiveTS)
+	es.conf.popCandidate(false)
+	c.report(1, normalSlowTrend())
+	c.advance(599 * time.Second)
+	c.heartbeat(1, 2, 3)
+	re.Empty(c.schedule(es))
+	re.Empty(removed)
+
+	// The scheduler removes itself once it's idle for too long, and only once.
+	c.advance(time.Second)
+	c.heartbeat(1, 2, 3)
+	re.Empty(c.schedule(es))
+	re.Equal([]string{EvictSlowTrendName}, removed)
+	c.advance(time.Second)
+	c.heartbeat(1, 2, 3)
+	re.Empty(c.schedule(es))
+	re.Len(removed, 1)
+}
+
+func TestEvictSlowTrendEvictedReason(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	annotate := func(storeID uint64, reason string) int {
+		data, err := json.Marshal(map[string]any{"store-id": storeID, "reason": reason})
+		re.NoError(err)
+		req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1/annotate", strings.NewReader(string(data)))
+		re.NoError(err)
+		resp := httptest.NewRecorder()
+		es.ServeHTTP(resp, req)
+		return resp.Code
+	}
+	listReasons := func() map[string]string {
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1/list", http.NoBody)
+		re.NoError(err)
+		resp := httptest.NewRecorder()
+		es.ServeHTTP(resp, req)
+		re.Equal(http.StatusOK, resp.Code)
+		var conf struct {
+			EvictedReasons map[string]string `json:"evicted-reasons"`
+		}
+		re.NoError(json.Unmarshal(resp.Body.Bytes(), &conf))
+		return conf.EvictedReasons
+	}
+	// Only the evicted stores can be annotated.
+	re.Equal(http.StatusBadRequest, annotate(1, "disk is failing"))
+
+	c.report(1, slowSlowTrend())
+	c.schedule(es)
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+	re.Equal(http.StatusOK, annotate(1, "disk is failing"))
+	re.Equal(http.StatusBadRequest, annotate(2, "disk is failing"))
+
+	// The reason is persisted and can be read back after a reload.
+	es.conf.EvictedReasons = nil
+	re.NoError(es.ReloadConfig())
+	re.Equal(map[string]string{"1": "disk is failing"}, listReasons())
+	// The config API can not modify the reasons.
+	_, _ = es.conf.update([]byte(`{"evicted-reasons":{"1":"modified"}}`))
+	re.Equal(map[string]string{"1": "disk is failing"}, listReasons())
+
+	// The reason is dropped once the store is recovered.
+	c.advance(time.Duration(es.conf.RecoveryDurationGap) * time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+	re.Empty(listReasons())
+}
+
+func TestEvictSlowTrendMinTrendDataAge(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.MinTrendDataAge = 120
+
+	// The slow trend of store-1 is ignored until the stores have been reporting
+	// the slow trends for long enough.
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.candidate())
+	re.False(es.conf.trendMature(1))
+	c.advance(119 * time.Second)
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.candidate())
+	c.advance(time.Second)
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+	c.advance(time.Second)
+	c.heartbeat(2, 3)
+	re.NotEmpty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.evictedStore())
+
+	// Store-1 restarts and looks fast, but it's not recovered until its slow
+	// trend matures again.
+	es.conf.RecoveryDurationGap = 0
+	c.advance(time.Second)
+	c.PutStore(c.GetStore(1).Clone(core.SetStoreStartTime(c.now.Unix())))
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	c.schedule(es)
+	re.Equal(uint64(1), es.conf.evictedStore())
+	re.False(es.conf.trendMature(1))
+	c.advance(120 * time.Second)
+	c.report(1, normalSlowTrend())
+	c.heartbeat(2, 3)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.evictedStore())
+}
+
+func TestEvictSlowTrendExcludeSlowTargets(t *testing.T) {
+	re := require.New(t)
+	// Store-1 and store-2 are both slow.
+	c := newSlowTrendTestClusterBuilder(re).
+		addStore(1, withTestSlowTrend(slowSlowTrend())).
+		addStore(2, withTestSlowTrend(slowSlowTrend())).
+		addStore(3).
+		addStore(4).
+		build()
+	defer c.close()
+	for regionID := uint64(11); regionID <= 20; regionID++ {
+		c.AddLeaderRegion(regionID, 1, 2, 3+regionID%2)
+	}
+	es := c.newScheduler()
+	re.NoError(es.prepareEvictLeader(c, 1))
+	re.Equal(map[uint64]struct{}{1: {}, 2: {}}, es.conf.excludedTargets(c))
+
+	// The leaders evicted from store-1 never land on store-2.
+	var count int
+	for i := 0; i < 20; i++ {
+		for _, op := range es.scheduleEvictLeader(c) {
+			step, ok := op.Step(0).(operator.TransferLeader)
+			re.True(ok)
+			re.Equal(uint64(1), step.FromStore)
+			re.NotEqual(uint64(2), step.ToStore)
+			re.NotContains(step.ToStores, uint64(2))
+			count++
+		}
+	}
+	re.Positive(count)
+}
+
+func TestNewEvictSlowTrendSchedulerWithConfig(t *testing.T) {
+	re := require.New(t)
+	cancel, _, _, oc := prepareSchedulersTest()
+	defer cancel()
+	storage := storage.NewStorageWithMemoryBackend()
+	s, err := NewEvictSlowTrendSchedulerWithConfig(oc, storage, []byte(`{"recovery-duration":1200,"flap-threshold":3,"flap-window":600}`))
+	re.NoError(err)
+	es, ok := s.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.Equal(uint64(1200), es.conf.RecoveryDurationGap)
+	re.Equal(uint64(3), es.conf.FlapThreshold)
+	re.Equal(uint64(600), es.conf.FlapWindow)
+	// The items not provided keep the defaults.
+	re.Equal(uint64(defaultFlapHoldDuration), es.conf.FlapHoldDuration)
+
+	// The provided config is persisted.
+	data, err := storage.LoadSchedulerConfig(EvictSlowTrendName)
+	re.NoError(err)
+	var persisted evictSlowTrendSchedulerConfig
+	re.NoError(json.Unmarshal([]byte(data), &persisted))
+	re.Equal(uint64(1200), persisted.RecoveryDurationGap)
+	re.Equal(uint64(3), persisted.FlapThreshold)
+
+	// The invalid config is rejected.
+	_, err = NewEvictSlowTrendSchedulerWithConfig(oc, storage, []byte(`{"comparison-method":"mean"}`))
+	re.Error(err)
+	_, err = NewEvictSlowTrendSchedulerWithConfig(oc, storage, []byte(`{`))
+	re.Error(err)
+}
+
+func TestEvictSlowTrendKV2JitterCounter(t *testing.T) {
+	re := require.New(t)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2"))
+	}()
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	add := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add")
+	addKV2Jitter := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_kv2_jitter")
+
+	// Store-1 was recovered just now, and it's still affected by the network
+	// jitters, which only shows in the `Duration` dimension.
+	c.report(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 5.0e3,
+		ResultRate:  0,
+	})
+	lastEvictCandidate := &slowCandidate{StoreID: 1, RecoverTS: c.now}
+	beforeAdd, beforeKV2Jitter := testutil.ToFloat64(add), testutil.ToFloat64(addKV2Jitter)
+	store := chooseEvictCandidate(c, c.GetStores(), es.conf, lastEvictCandidate)
+	re.NotNil(store)
+	re.Equal(uint64(1), store.GetID())
+	// The standard counter is only increased once the candidate is confirmed,
+	// but not when it's pre-captured.
+	re.Equal(beforeAdd+1, testutil.ToFloat64(add))
+	re.Equal(beforeKV2Jitter+1, testutil.ToFloat64(addKV2Jitter))
+
+	// The disk jitters are counted by the standard counter when pre-captured.
+	c.report(1, slowSlowTrend())
+	store = chooseEvictCandidate(c, c.GetStores(), es.conf, lastEvictCandidate)
+	re.NotNil(store)
+	re.Equal(beforeAdd+3, testutil.ToFloat64(add))
+	re.Equal(beforeKV2Jitter+1, testutil.ToFloat64(addKV2Jitter))
+}
+
+func TestEvictSlowTrendKV2JitterZeroRecoverTS(t *testing.T) {
+	re := require.New(t)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2"))
+	}()
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	addKV2Jitter := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "add_kv2_jitter")
+
+	// Store-1 was the last candidate but was never evicted or recovered, so the
+	// network jitters are not re-checked.
+	c.report(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 5.0e3,
+		ResultRate:  0,
+	})
+	lastEvictCandidate := &slowCandidate{StoreID: 1, CaptureTS: c.now}
+	before := testutil.ToFloat64(addKV2Jitter)
+	re.Nil(chooseEvictCandidate(c, c.GetStores(), es.conf, lastEvictCandidate))
+	re.Equal(before, testutil.ToFloat64(addKV2Jitter))
+
+	// It's re-checked once it's recovered.
+	lastEvictCandidate.RecoverTS = c.now
+	re.NotNil(chooseEvictCandidate(c, c.GetStores(), es.conf, lastEvictCandidate))
+	re.Equal(before+1, testutil.ToFloat64(addKV2Jitter))
+}
+
+func TestEvictSlowTrendCandidateCancelCooldown(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	es.conf.CandidateCancelCooldown = 60
+	cooldown := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_cancel_cooldown")
+
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+	// The candidate is canceled as the trend dips.
+	c.advance(time.Second)
+	c.report(1, normalSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.candidate())
+
+	// It's not re-captured within the cooldown.
+	before := testutil.ToFloat64(cooldown)
+	c.advance(59 * time.Second)
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.candidate())
+	re.Equal(before+1, testutil.ToFloat64(cooldown))
+
+	c.advance(time.Second)
+	c.report(1, slowSlowTrend())
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+}
+
+func TestEvictSlowTrendRegionAwareAffectedCount(t *testing.T) {
+	re := require.New(t)
+	degraded := &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0.0,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+	// Store-1 leads region-1 with the followers on store-2 and store-3.
+	newCluster := func(degradedStores ...uint64) *slowTrendTestCluster {
+		b := newSlowTrendTestClusterBuilder(re)
+		for storeID := uint64(1); storeID <= 10; storeID++ {
+			b.addStore(storeID)
+		}
+		c := b.build()
+		c.report(1, slowSlowTrend())
+		for _, storeID := range degradedStores {
+			c.report(storeID, degraded)
+		}
+		return c
+	}
+	affectAFew := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_affect_a_few")
+
+	// Store-7 and store-8 are slow coincidentally, without sharing any region
+	// with store-1.
+	c := newCluster(7, 8)
+	defer c.close()
+	es := c.newScheduler()
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+
+	c = newCluster(7, 8)
+	defer c.close()
+	es = c.newScheduler()
+	es.conf.RegionAwareAffectedCount = true
+	before := testutil.ToFloat64(affectAFew)
+	re.Empty(c.schedule(es))
+	re.Zero(es.conf.candidate())
+	re.Equal(before+1, testutil.ToFloat64(affectAFew))
+
+	// Store-2 and store-3 host the replicas of the leader regions of store-1.
+	c = newCluster(2, 3)
+	defer c.close()
+	es = c.newScheduler()
+	es.conf.RegionAwareAffectedCount = true
+	re.Empty(c.schedule(es))
+	re.Equal(uint64(1), es.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendPrepare() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.Zero(es2.conf.evictedStore())
+	// prepare with no evict store.
+	suite.es.PrepareConfig(suite.tc)
+
+	es2.conf.setStoreAndPersist(1)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	// prepare with evict store.
+	suite.es.PrepareConfig(suite.tc)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendWithSlowScore() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+
+	suite.tc.AddLeaderStore(4, 10)
+	suite.tc.AddLeaderStore(5, 10)
+	for storeID := uint64(4); storeID <= 5; storeID++ {
+		storeInfo := suite.tc.GetStore(storeID)
+		newStoreInfo := storeInfo.Clone(func(store *core.StoreInfo) {
+			store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
+				CauseValue:  5.0e6,
+				CauseRate:   0.0,
+				ResultValue: 5.0e3,
+				ResultRate:  0.0,
+			}
+		})
+		suite.tc.PutStore(newStoreInfo)
+	}
+	// Set store-1 and store-2 to slow status, store-2 is slower by trend but
+	// store-1 has the higher slow score.
+	slowScores := map[uint64]uint64{1: 80, 2: 10}
+	causeValues := map[uint64]float64{1: 5.0e8, 2: 6.0e8}
+	for storeID := uint64(1); storeID <= 2; storeID++ {
+		storeInfo := suite.tc.GetStore(storeID)
+		newStoreInfo := storeInfo.Clone(func(store *core.StoreInfo) {
+			store.GetStoreStats().SlowScore = slowScores[storeID]
+			store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
+				CauseValue:  causeValues[storeID],
+				CauseRate:   1e7,
+				ResultValue: 3.0e3,
+				ResultRate:  -1e7,
+			}
+		})
+		suite.tc.PutStore(newStoreInfo)
+	}
+
+	// Too many candidates without the slow score.
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	// The store with the higher slow score is chosen.
+	es2.conf.UseSlowScore = true
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendResultFieldsOptional() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+
+	// Set store-1 to slow status, but only the cause fields are reported.
+	storeInfo := suite.tc.GetStore(1)
+	newStoreInfo := storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
+			CauseValue: 5.0e8,
+			CauseRate:  1e7,
+		}
+	})
+	suite.tc.PutStore(newStoreInfo)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	// Fall back to the cause-only pattern.
+	es2.conf.ResultFieldsOptional = true
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendCandidateResultCounter() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	canceled := testutil.ToFloat64(slowTrendCandidateCanceledCounter)
+	evicted := testutil.ToFloat64(slowTrendCandidateEvictedCounter)
+
+	// Capture store-1 which is only a little slower than others, then cancel it.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e6 + 100,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(canceled+1, testutil.ToFloat64(slowTrendCandidateCanceledCounter))
+	re.Equal(evicted, testutil.ToFloat64(slowTrendCandidateEvictedCounter))
+
+	// Capture store-1 again and evict it.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	re.Equal(canceled+1, testutil.ToFloat64(slowTrendCandidateCanceledCounter))
+	re.Equal(evicted+1, testutil.ToFloat64(slowTrendCandidateEvictedCounter))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendCandidateExitCounter() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	exits := map[string]prometheus.Counter{
+		"too_faster":      slowTrendCandidateExitTooFasterCounter,
+		"not_confirmed":   slowTrendCandidateExitNotConfirmedCounter,
+		"removed":         slowTrendCandidateExitRemovedCounter,
+		"already_evicted": slowTrendCandidateExitAlreadyEvictedCounter,
+		"prepare_failed":  slowTrendCandidateExitPrepareFailedCounter,
+		"evicted":         slowTrendCandidateExitEvictedCounter,
+	}
+	checkExits := func(expected map[string]float64) {
+		for reason, counter := range exits {
+			re.Equal(expected[reason], testutil.ToFloat64(counter), reason)
+		}
+	}
+	base := make(map[string]float64)
+	for reason, counter := range exits {
+		base[reason] = testutil.ToFloat64(counter)
+	}
+
+	// Capture store-1 which is only a little slower than others, then cancel it
+	// because it's faster than others in the next tick.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e6 + 100,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	checkExits(base)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	base["too_faster"]++
+	checkExits(base)
+
+	// Capture store-1 again, wait for the heartbeats of other stores, then evict it.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	checkExits(base)
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	base["evicted"]++
+	checkExits(base)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendClusterWideSlow() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.ClusterSlowCauseValueCeiling = 1.0e8
+
+	// All stores are slow together.
+	for storeID := uint64(2); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e8,
+			CauseRate:   0.0,
+			ResultValue: 5.0e3,
+			ResultRate:  0.0,
+		})
+	}
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  9.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	// Only store-1 is slow.
+	for storeID := uint64(2); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0.0,
+			ResultValue: 5.0e3,
+			ResultRate:  0.0,
+		})
+	}
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendRebalanceOnRecover() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	es2.conf.RebalanceOnRecover = true
+
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	suite.updateStoresHeartbeat(1)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Zero(es2.conf.evictedStore())
+	re.Len(ops, 2)
+	for _, op := range ops {
+		re.Equal(EvictSlowTrendType, op.Desc())
+		re.Equal(uint64(1), op.Step(0).(operator.TransferLeader).ToStore)
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendEvictionBudget() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	es2.conf.MaxEvictionsPerWindow = 2
+
+	// Exhaust the eviction budget.
+	for i := 0; i < 2; i++ {
+		re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+		es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	}
+	re.Len(es2.conf.RecentEvictions, 2)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	// The budget is available again after the window advances.
+	now = now.Add(defaultEvictionBudgetWindow * time.Second)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	re.Len(es2.conf.RecentEvictions, 1)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendWriteStall() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+
+	// Store-1 keeps write stalling but its slow trend is normal.
+	suite.tc.SetStoreBusy(1, true)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	now = now.Add(defaultWriteStallDuration * time.Second)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	// The write stall has not lasted long enough yet.
+	es2.conf.EvictOnWriteStall = true
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	now = now.Add(defaultWriteStallDuration * time.Second)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendConfirmationSamples() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	es2.conf.ConfirmationSamples = 2
+	slowTrend := &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+
+	// Store-1 is slow in the first window only.
+	suite.setStoreSlowTrend(1, slowTrend)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	// Its latency is still high, but it's not getting worse anymore.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   0.0,
+		ResultValue: 3.0e3,
+		ResultRate:  0.0,
+	})
+	now = now.Add(defaultConfirmationInterval * time.Second)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Zero(es2.conf.evictedStore())
+
+	// Store-1 is slow in both windows.
+	suite.setStoreSlowTrend(1, slowTrend)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	now = now.Add(defaultConfirmationInterval * time.Second)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendScatterOnEvict() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.ScatterOnEvict = true
+
+	suite.tc.AddLeaderStore(4, 10)
+	suite.tc.AddLeaderStore(5, 10)
+	suite.tc.AddLeaderRegion(1, 1, 2, 3, 4, 5)
+	for regionID := uint64(4); regionID <= 12; regionID++ {
+		suite.tc.AddLeaderRegion(regionID, 1, 2, 3, 4, 5)
+	}
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	for i := 0; i < 10; i++ {
+		ops := es2.scheduleEvictLeader(suite.tc)
+		re.NotEmpty(ops)
+		targets := make(map[uint64]int)
+		for _, op := range ops {
+			targets[op.Step(0).(operator.TransferLeader).ToStore]++
+		}
+		// The leaders are distributed across the targets.
+		re.Len(targets, min(len(ops), 4))
+		for _, count := range targets {
+			re.LessOrEqual(count, (len(ops)+3)/4)
+		}
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendCriticalRegion() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	skipped := storeSlowTrendActionStatusGauge.WithLabelValues("evict", "skip_critical_region")
+	// Region 1 is critical, both its leader and the leader of region 4 are on store 1.
+	region := suite.tc.GetRegion(1)
+	es2.conf.CriticalKeyRanges = []core.KeyRange{core.NewKeyRange(string(region.GetStartKey()), string(region.GetEndKey()))}
+	suite.tc.AddLeaderRegion(4, 1, 2, 3)
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+
+	checkEvictedRegions := func(expected uint64) {
+		for i := 0; i < 10; i++ {
+			ops := es2.scheduleEvictLeader(suite.tc)
+			re.NotEmpty(ops)
+			for _, op := range ops {
+				re.Equal(expected, op.RegionID())
+			}
+		}
+	}
+	count := testutil.ToFloat64(skipped)
+	checkEvictedRegions(4)
+	re.Greater(testutil.ToFloat64(skipped), count)
+
+	// The leader of the critical region is never evicted by default.
+	suite.tc.AddLeaderRegion(4, 2, 1, 3)
+	re.Empty(es2.scheduleEvictLeader(suite.tc))
+
+	// It's evicted after the others with the "last" policy.
+	es2.conf.CriticalRegionPolicy = criticalRegionPolicyLast
+	checkEvictedRegions(1)
+	es2.conf.CriticalRegionPolicy = "first"
+	re.Error(es2.conf.validateLocked())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendSlowKind() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	mockRaftKV2 := "github.com/tikv/pd/pkg/schedule/schedulers/mockRaftKV2"
+	re.NoError(failpoint.Enable(mockRaftKV2, "return(true)"))
+	for storeID := uint64(2); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0,
+			ResultValue: 5.0e3,
+			ResultRate:  -1e7,
+		})
+	}
+	diskSlow := &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+	// It's slow on the cause but not on the result, and it has just recovered.
+	networkSlow := &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  0,
+	}
+	lastEvictCandidate := &slowCandidate{StoreID: 1, RecoverTS: time.Now()}
+
+	for _, tc := range []struct {
+		enableDisk    bool
+		enableNetwork bool
+	}{
+		{true, true},
+		{true, false},
+		{false, true},
+		{false, false},
+	} {
+		es2.conf.EnableDiskSlowEvict = tc.enableDisk
+		es2.conf.EnableNetworkSlowEvict = tc.enableNetwork
+		for _, kind := range []struct {
+			slowTrend *pdpb.SlowTrend
+			enabled   bool
+			capture   prometheus.Counter
+			alert     prometheus.Counter
+		}{
+			{diskSlow, tc.enableDisk, slowTrendDiskSlowCaptureCounter, slowTrendDiskSlowAlertCounter},
+			{networkSlow, tc.enableNetwork, slowTrendNetworkSlowCaptureCounter, slowTrendNetworkSlowAlertCounter},
+		} {
+			suite.setStoreSlowTrend(1, kind.slowTrend)
+			captured, alerted := testutil.ToFloat64(kind.capture), testutil.ToFloat64(kind.alert)
+			candidate := chooseEvictCandidate(suite.tc, suite.tc.GetStores(), es2.conf, lastEvictCandidate)
+			if kind.enabled {
+				re.NotNil(candidate)
+				re.Equal(uint64(1), candidate.GetID())
+				re.Equal(captured+1, testutil.ToFloat64(kind.capture))
+				re.Equal(alerted, testutil.ToFloat64(kind.alert))
+			} else {
+				re.Nil(candidate)
+				re.Equal(captured, testutil.ToFloat64(kind.capture))
+				re.Equal(alerted+1, testutil.ToFloat64(kind.alert))
+			}
+		}
+	}
+	re.NoError(failpoint.Disable(mockRaftKV2))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendFailureDomain() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	sameDomain := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_same_failure_domain")
+	// Store-4 and store-5 are slow in the same rack, store-4 is slower.
+	for storeID := uint64(4); storeID <= 5; storeID++ {
+		suite.tc.AddLabelsStore(storeID, 10, map[string]string{"rack": "r1"})
+		suite.tc.UpdateLeaderCount(storeID, 10)
+	}
+	suite.tc.AddLeaderRegion(4, 4, 1, 2)
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0,
+			ResultValue: 5.0e3,
+			ResultRate:  -1e7,
+		})
+	}
+	suite.setStoreSlowTrend(4, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	suite.setStoreSlowTrend(5, &pdpb.SlowTrend{
+		CauseValue:  4.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	re.Nil(chooseEvictCandidate(suite.tc, suite.tc.GetStores(), es2.conf, nil))
+
+	// Only the slower one in the rack is evicted.
+	es2.conf.FailureDomainLabel = "rack"
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(4), es2.conf.candidate())
+	suite.updateStoresHeartbeat(1, 2, 3, 5)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal([]uint64{4}, es2.conf.getStores())
+
+	// The other one can not be evicted while store-4 is evicted.
+	suite.setStoreSlowTrend(4, &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0,
+		ResultValue: 5.0e3,
+		ResultRate:  -1e7,
+	})
+	count := testutil.ToFloat64(sameDomain)
+	re.Nil(chooseEvictCandidate(suite.tc, suite.tc.GetStores(), es2.conf, nil))
+	re.Equal(count+1, testutil.ToFloat64(sameDomain))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendSoftEvict() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.SoftEvict = true
+	suite.tc.UpdateStoreLeaderWeight(1, 2)
+	// Store-1 is 4 times slower than the others.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  2.0e7,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+
+	// The leaders are not evicted, but the leader weight is lowered.
+	re.Equal(0.5, suite.tc.GetStore(1).GetLeaderWeight())
+	re.Empty(es2.scheduleEvictLeader(suite.tc))
+	re.Equal(0.5, suite.tc.GetStore(1).GetLeaderWeight())
+	re.Empty(es2.scheduleEvictLeader(suite.tc))
+	re.Equal(0.5, suite.tc.GetStore(1).GetLeaderWeight())
+	// The store is not marked as evicted, so balance-leader can still move
+	// leaders to it as long as its score with the lowered weight is lower.
+	re.False(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	ops, _ := suite.bs.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	operatorutil.CheckTransferLeader(re, ops[0], operator.OpLeader, 3, 1)
+	re.False(es2.VerifyState(suite.tc, false).hasDrift())
+
+	// The original weight is persisted, and it's restored on recovery by the
+	// scheduler of the new leader.
+	re.Equal(leaderWeightChange{StoreID: 1, OriginalWeight: 2, Weight: 0.5}, es2.conf.softEvictedWeight())
+	sche, err := CreateScheduler(EvictSlowTrendType, suite.oc, es2.conf.storage, ConfigSliceDecoder(EvictSlowTrendType, []string{}))
+	re.NoError(err)
+	re.NoError(sche.ReloadConfig())
+	newES, ok := sche.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.NoError(newES.PrepareConfig(suite.tc))
+	re.False(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.Empty(newES.scheduleEvictLeader(suite.tc))
+	re.Equal(0.5, suite.tc.GetStore(1).GetLeaderWeight())
+	newES.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	re.Equal(2.0, suite.tc.GetStore(1).GetLeaderWeight())
+	re.Zero(newES.conf.softEvictedWeight())
+	re.False(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	newES.CleanConfig(suite.tc)
+
+	// The weight changed by others during the eviction is not overwritten.
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	re.Empty(es2.scheduleEvictLeader(suite.tc))
+	re.Equal(0.5, suite.tc.GetStore(1).GetLeaderWeight())
+	re.NoError(suite.tc.SetStoreWeight(1, 3, 1))
+	es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	re.Equal(3.0, suite.tc.GetStore(1).GetLeaderWeight())
+	re.Zero(es2.conf.softEvictedWeight())
+
+	// The leaders are evicted if the weight can't be persisted by the cluster,
+	// e.g., in the scheduling service.
+	cluster := &weightUnawareCluster{SchedulerCluster: suite.tc}
+	re.NoError(es2.prepareEvictLeader(cluster, 1))
+	re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.NotEmpty(es2.scheduleEvictLeader(cluster))
+	re.Equal(3.0, suite.tc.GetStore(1).GetLeaderWeight())
+	es2.cleanupEvictLeader(cluster, evictCleanupReasonRecovered)
+	re.False(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+
+	// The soft evicted store is evicted fully once the soft eviction is turned
+	// off, and its leader weight is restored.
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	re.Equal(0.75, suite.tc.GetStore(1).GetLeaderWeight())
+	es2.conf.SoftEvict = false
+	re.NotEmpty(es2.scheduleEvictLeader(suite.tc))
+	re.Equal(3.0, suite.tc.GetStore(1).GetLeaderWeight())
+	re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.Zero(es2.conf.softEvictedWeight())
+	es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	re.False(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.NotEmpty(es2.scheduleEvictLeader(suite.tc))
+	re.Equal(3.0, suite.tc.GetStore(1).GetLeaderWeight())
+}
+
+// weightUnawareCluster is a cluster which can't persist the store weights.
+type weightUnawareCluster struct {
+	sche.SchedulerCluster
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendEvictOperatorLimit() {
+	re := suite.Require()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := hbstream.NewTestHeartbeatStreams(ctx, suite.tc.ID, suite.tc, false)
+	oc := operator.NewController(ctx, suite.tc.GetBasicCluster(), suite.tc.GetSchedulerConfig(), stream)
+	es, err := CreateScheduler(EvictSlowTrendType, oc, storage.NewStorageWithMemoryBackend(), ConfigSliceDecoder(EvictSlowTrendType, []string{}))
+	re.NoError(err)
+	es2, ok := es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	for i := uint64(4); i <= 6; i++ {
+		suite.tc.AddLeaderRegion(i, 1, 2, 3)
+	}
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	// The leader schedule limit is used up by balance-leader.
+	suite.tc.SetLeaderScheduleLimit(1)
+	op, err := operator.CreateTransferLeaderOperator(BalanceLeaderType, suite.tc, suite.tc.GetRegion(2), 3, []uint64{}, operator.OpLeader)
+	re.NoError(err)
+	re.True(oc.AddOperator(op))
+	re.False(es.IsScheduleAllowed(suite.tc))
+
+	// The eviction proceeds up to its own limit.
+	es2.conf.EvictOperatorLimit = 2
+	for i := 0; i < 2; i++ {
+		re.True(es.IsScheduleAllowed(suite.tc))
+		// The regions are picked randomly, and may have been picked in the
+		// previous batch.
+		added := false
+		for retry := 0; retry < 100 && !added; retry++ {
+			for _, op := range es2.scheduleEvictLeader(suite.tc) {
+				if added = oc.AddOperator(op); added {
+					break
+				}
+			}
+		}
+		re.True(added)
+	}
+	re.False(es.IsScheduleAllowed(suite.tc))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendHostLevelSlow() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	hostLevelSlow := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_host_level_slow")
+	// Store-4 and store-5 are on the same host, and both of them are slow.
+	for storeID := uint64(4); storeID <= 5; storeID++ {
+		suite.tc.AddLeaderStore(storeID, 10)
+		address := fmt.Sprintf("192.168.0.1:2016%d", storeID)
+		suite.tc.PutStore(suite.tc.GetStore(storeID).Clone(core.SetStoreAddress(address, address, address)))
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e8,
+			CauseRate:   1e7,
+			ResultValue: 3.0e3,
+			ResultRate:  -1e7,
+		})
+	}
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0,
+			ResultValue: 5.0e3,
+			ResultRate:  -1e7,
+		})
+	}
+	es2.conf.PickWorstCandidate = true
+
+	count := testutil.ToFloat64(hostLevelSlow)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(count+1, testutil.ToFloat64(hostLevelSlow))
+
+	// The slow store is captured if its sibling is not slow.
+	suite.setStoreSlowTrend(5, &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0,
+		ResultValue: 5.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(4), es2.conf.candidate())
+	re.Equal(count+1, testutil.ToFloat64(hostLevelSlow))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendEvictRamp() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	for i := uint64(4); i <= 100; i++ {
+		suite.tc.AddLeaderRegion(i, 1, 2, 3)
+	}
+	re.Equal(EvictLeaderBatchSize, es2.conf.evictBatchSize())
+	es2.conf.EvictRampInitialBatch = 1
+	es2.conf.EvictRampStep = 1
+	es2.conf.EvictRampInterval = 60
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+
+	start := now
+	for _, tc := range []struct {
+		elapsed   time.Duration
+		batchSize int
+	}{
+		{0, 1},
+		{59 * time.Second, 1},
+		{time.Minute, 2},
+		{2 * time.Minute, 3},
+		{time.Hour, EvictLeaderBatchSize},
+	} {
+		now = start.Add(tc.elapsed)
+		re.Equal(tc.batchSize, es2.conf.evictBatchSize())
+		// The same region may be picked more than once in a batch.
+		ops := es2.scheduleEvictLeader(suite.tc)
+		re.NotEmpty(ops)
+		re.LessOrEqual(len(ops), tc.batchSize)
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendKeyRanges() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	// Only region 4 is in the key ranges, both its leader and the leader of
+	// region 1 are on store 1.
+	region := suite.tc.AddLeaderRegion(4, 1, 2, 3)
+	es2.conf.KeyRanges = []core.KeyRange{core.NewKeyRange(string(region.GetStartKey()), string(region.GetEndKey()))}
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+
+	for i := 0; i < 10; i++ {
+		ops := es2.scheduleEvictLeader(suite.tc)
+		re.NotEmpty(ops)
+		for _, op := range ops {
+			re.Equal(uint64(4), op.RegionID())
+		}
+	}
+
+	// The leaders out of the key ranges are left put.
+	suite.tc.AddLeaderRegion(4, 2, 1, 3)
+	re.Empty(es2.scheduleEvictLeader(suite.tc))
+	re.Equal(uint64(1), suite.tc.GetRegion(1).GetLeader().GetStoreId())
+
+	es2.conf.KeyRanges = nil
+	ops := es2.scheduleEvictLeader(suite.tc)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), ops[0].RegionID())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendEngineFilter() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	filtered := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_engine_filtered")
+	suite.tc.AddLabelsStore(4, 0, map[string]string{core.EngineKey: core.EngineTiFlash})
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0,
+			ResultValue: 5.0e3,
+			ResultRate:  -1e7,
+		})
+	}
+	suite.setStoreSlowTrend(4, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+
+	// The slow TiFlash store is ignored if only TiKV stores can be captured.
+	es2.conf.EngineFilter = core.EngineTiKV
+	count := testutil.ToFloat64(filtered)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(count+1, testutil.ToFloat64(filtered))
+
+	es2.conf.EngineFilter = ""
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(4), es2.conf.candidate())
+	re.Equal(count+1, testutil.ToFloat64(filtered))
+
+	es2.conf.EngineFilter = "raft"
+	re.Error(es2.conf.validateLocked())
+}
+
+type restoringCluster struct {
+	*mockcluster.Cluster
+	restoring bool
+}
+
+func (c *restoringCluster) IsSnapshotRecovering() bool {
+	return c.restoring
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendDeferCaptureWhileRestoring() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	deferred := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_restore_in_progress")
+	cluster := &restoringCluster{Cluster: suite.tc, restoring: true}
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0,
+			ResultValue: 5.0e3,
+			ResultRate:  -1e7,
+		})
+	}
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+
+	count := testutil.ToFloat64(deferred)
+	ops, _ := suite.es.Schedule(cluster, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(count+1, testutil.ToFloat64(deferred))
+
+	cluster.restoring = false
+	ops, _ = suite.es.Schedule(cluster, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	re.Equal(count+1, testutil.ToFloat64(deferred))
+
+	// The active eviction is still managed during the restore.
+	es2.conf.popCandidate(false)
+	es2.conf.EvictedStores = []uint64{1}
+	cluster.restoring = true
+	ops, _ = suite.es.Schedule(cluster, false)
+	operatorutil.CheckMultiTargetTransferLeader(re, ops[0], operator.OpLeader, 1, []uint64{2, 3})
+	re.Equal(count+1, testutil.ToFloat64(deferred))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendClearEvictedStore() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	suite.tc.AddLeaderStore(4, 10)
+	suite.tc.AddLeaderStore(5, 10)
+	es2.conf.EvictedStores = []uint64{1, 4, 5}
+	for _, storeID := range es2.conf.EvictedStores {
+		re.NoError(suite.tc.SlowTrendEvicted(storeID))
+	}
+
+	re.NoError(es2.ClearEvictedStore(suite.tc, 4))
+	re.Equal([]uint64{1, 5}, es2.conf.getStores())
+	re.False(suite.tc.GetStore(4).IsEvictedAsSlowTrend())
+	re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.True(suite.tc.GetStore(5).IsEvictedAsSlowTrend())
+	// Clearing a store which is not evicted fails.
+	re.Error(es2.ClearEvictedStore(suite.tc, 4))
+	re.Equal([]uint64{1, 5}, es2.conf.getStores())
+
+	// The evicted stores are persisted.
+	var persisted evictSlowTrendSchedulerConfig
+	_, values, err := es2.conf.storage.LoadAllSchedulerConfigs()
+	re.NoError(err)
+	re.Len(values, 1)
+	re.NoError(json.Unmarshal([]byte(values[0]), &persisted))
+	re.Equal([]uint64{1, 5}, persisted.EvictedStores)
+
+	// The clearance is rolled back if it fails to be persisted, so the store is
+	// still tracked and evicted.
+	persistFail := "github.com/tikv/pd/pkg/schedule/schedulers/persistFail"
+	es2.conf.PersistFailurePolicy = persistFailurePolicyRollback
+	evicted, err := es2.conf.annotateAndPersist(5, "disk")
+	re.NoError(err)
+	re.True(evicted)
+	re.NoError(failpoint.Enable(persistFail, "return(true)"))
+	re.Error(es2.ClearEvictedStore(suite.tc, 5))
+	re.NoError(failpoint.Disable(persistFail))
+	re.Equal([]uint64{1, 5}, es2.conf.getStores())
+	re.Equal("disk", es2.conf.evictedReason(5))
+	re.True(suite.tc.GetStore(5).IsEvictedAsSlowTrend())
+	re.False(es2.conf.persistPending)
+
+	// The clearance is kept and persisted later, so the store is recovered.
+	es2.conf.PersistFailurePolicy = persistFailurePolicyRetry
+	re.NoError(failpoint.Enable(persistFail, "return(true)"))
+	re.NoError(es2.ClearEvictedStore(suite.tc, 5))
+	re.NoError(failpoint.Disable(persistFail))
+	re.Equal([]uint64{1}, es2.conf.getStores())
+	re.False(suite.tc.GetStore(5).IsEvictedAsSlowTrend())
+	re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.True(es2.conf.persistPending)
+	re.NoError(es2.conf.retryPersist())
+	_, values, err = es2.conf.storage.LoadAllSchedulerConfigs()
+	re.NoError(err)
+	re.NoError(json.Unmarshal([]byte(values[0]), &persisted))
+	re.Equal([]uint64{1}, persisted.EvictedStores)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendVerifyState() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	suite.tc.AddLeaderStore(4, 10)
+	es2.conf.EvictedStores = []uint64{1, 4}
+	re.NoError(suite.tc.SlowTrendEvicted(1))
+	re.NoError(suite.tc.SlowTrendEvicted(2))
+	// Store-4 is persisted but not applied, and store-2 is applied but not persisted.
+	drift := es2.VerifyState(suite.tc, false)
+	re.Equal([]uint64{4}, drift.PersistedOnly)
+	re.Equal([]uint64{2}, drift.AppliedOnly)
+	re.False(drift.Repaired)
+	re.False(suite.tc.GetStore(4).IsEvictedAsSlowTrend())
+	re.True(suite.tc.GetStore(2).IsEvictedAsSlowTrend())
+
+	verify := func(method string) *SlowTrendStateDrift {
+		req, err := http.NewRequest(method, "http://127.0.0.1/verify", http.NoBody)
+		re.NoError(err)
+		resp := httptest.NewRecorder()
+		suite.es.ServeHTTP(resp, req)
+		re.Equal(http.StatusOK, resp.Code)
+		var drift SlowTrendStateDrift
+		re.NoError(json.Unmarshal(resp.Body.Bytes(), &drift))
+		return &drift
+	}
+	drift = verify(http.MethodGet)
+	re.Equal([]uint64{4}, drift.PersistedOnly)
+	re.Equal([]uint64{2}, drift.AppliedOnly)
+	re.False(drift.Repaired)
+
+	// Repair the cluster to match the persisted state.
+	drift = verify(http.MethodPost)
+	re.True(drift.Repaired)
+	re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.True(suite.tc.GetStore(4).IsEvictedAsSlowTrend())
+	re.False(suite.tc.GetStore(2).IsEvictedAsSlowTrend())
+	re.Equal([]uint64{1, 4}, es2.conf.getStores())
+
+	drift = verify(http.MethodGet)
+	re.Empty(drift.PersistedOnly)
+	re.Empty(drift.AppliedOnly)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendComparisonEpsilon() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.Equal(alterEpsilon, es2.conf.ComparisonEpsilon)
+	// Store-1 is only marginally slow.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e-3,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e-3,
+	})
+
+	// The marginal trend is filtered by a larger epsilon.
+	es2.conf.ComparisonEpsilon = 1e-2
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	es2.conf.ComparisonEpsilon = alterEpsilon
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+
+	es2.conf.ComparisonEpsilon = 0
+	re.Error(es2.conf.validateLocked())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendMinResultRateDrop() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.MinResultRateDrop = 1e3
+
+	// The QPS of store-1 only drops slightly.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -10,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+
+	es2.conf.MinResultRateDrop = -1
+	re.Error(es2.conf.validateLocked())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendAffectedStoreGauge() {
+	re := suite.Require()
+	affectedCount := storeSlowTrendMiscGauge.WithLabelValues("store", "affected_count")
+	affectedThreshold := storeSlowTrendMiscGauge.WithLabelValues("store", "affected_threshold")
+
+	suite.es.Schedule(suite.tc, false)
+	re.Zero(testutil.ToFloat64(affectedCount))
+	threshold := float64(int(3 * suite.tc.GetSchedulerConfig().GetSlowStoreEvictingAffectedStoreRatioThreshold()))
+	re.Equal(threshold, testutil.ToFloat64(affectedThreshold))
+
+	for storeID := uint64(1); storeID <= 2; storeID++ {
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0.0,
+			ResultValue: 3.0e3,
+			ResultRate:  -1e7,
+		})
+	}
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(2.0, testutil.ToFloat64(affectedCount))
+	re.Equal(threshold, testutil.ToFloat64(affectedThreshold))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendStoreRemoved() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.NoError(suite.es.PrepareConfig(suite.tc))
+	defer suite.es.CleanConfig(suite.tc)
+
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	// Removing other stores does not affect the evicted one.
+	suite.tc.DeleteStore(suite.tc.GetStore(3))
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	// The evicted store is cleaned up without scheduling.
+	suite.tc.DeleteStore(suite.tc.GetStore(1))
+	re.Zero(es2.conf.evictedStore())
+
+	es2.conf.captureCandidate(2)
+	suite.tc.DeleteStore(suite.tc.GetStore(2))
+	re.Zero(es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendRecoverWithStaleData() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	staleData := storeSlowTrendActionStatusGauge.WithLabelValues("recover", "reject_stale_data")
+	rejected := testutil.ToFloat64(staleData)
+
+	// Store-1 looks as fast as others, but its data is reported before eviction.
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	re.Equal(rejected+1, testutil.ToFloat64(staleData))
+
+	// Store-1 reports the fresh data.
+	suite.updateStoresHeartbeat(1)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.evictedStore())
+	re.Equal(rejected+1, testutil.ToFloat64(staleData))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendLogLevel() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	logCore, logs := observer.New(zapcore.InfoLevel)
+	es2.conf.logger = zap.New(logCore)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	continueMsg := "store evicted by slow trend is still evicted"
+
+	// The routine logs are suppressed at the normal level.
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Zero(logs.FilterMessage(continueMsg).Len())
+
+	es2.conf.LogLevel = slowTrendLogLevelVerbose
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(1, logs.FilterMessage(continueMsg).Len())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendLongitudinal() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.DetectionMode = slowTrendDetectionModeLongitudinal
+	setCauseValues := func(causeValues map[uint64]float64) {
+		for storeID, causeValue := range causeValues {
+			suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+				CauseValue:  causeValue,
+				CauseRate:   0.0,
+				ResultValue: 5.0e3,
+				ResultRate:  0.0,
+			})
+		}
+	}
+
+	// Initialize the baselines.
+	setCauseValues(map[uint64]float64{1: 5.0e6, 2: 2.5e8, 3: 2.5e8})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	// All stores are equally slow, but store-1 regressed most against itself.
+	setCauseValues(map[uint64]float64{1: 5.0e8, 2: 5.0e8, 3: 5.0e8})
+	es2.conf.DetectionMode = slowTrendDetectionModeCrossSectional
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	es2.conf.DetectionMode = slowTrendDetectionModeLongitudinal
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendPause() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	paused := testutil.ToFloat64(evictSlowTrendPausedCounter)
+
+	// Nothing is captured while paused.
+	re.NoError(es2.Pause(time.Minute))
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(paused+1, testutil.ToFloat64(evictSlowTrendPausedCounter))
+
+	// The pause deadline survives the reloading.
+	sche, err := CreateScheduler(EvictSlowTrendType, suite.oc, es2.conf.storage, ConfigSliceDecoder(EvictSlowTrendType, []string{}))
+	re.NoError(err)
+	re.NoError(sche.ReloadConfig())
+	re.Equal(es2.conf.PausedUntil.Unix(), sche.(*evictSlowTrendScheduler).conf.PausedUntil.Unix())
+
+	// Resume automatically after the duration.
+	now = now.Add(time.Minute)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	re.True(es2.conf.PausedUntil.IsZero())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+
+	// The eviction is kept but not changed while paused, even if the store has
+	// been recovered.
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	re.NoError(es2.Pause(time.Minute))
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0.0,
+		ResultValue: 5.0e3,
+		ResultRate:  0.0,
+	})
+	suite.updateStoresHeartbeat(1)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	re.Equal(paused+2, testutil.ToFloat64(evictSlowTrendPausedCounter))
+
+	re.NoError(es2.Resume())
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.evictedStore())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendConsiderPreparingStores() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+
+	// Scale out store-4, which is still preparing and slower than others.
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		suite.tc.PutStore(suite.tc.GetStore(storeID).Clone(core.SetStoreState(metapb.StoreState_Up)))
+	}
+	suite.tc.AddLeaderStore(4, 0)
+	re.True(suite.tc.GetStore(4).IsPreparing())
+	suite.setStoreSlowTrend(4, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0.0,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+
+	es2.conf.ConsiderPreparingStores = false
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	es2.conf.ConsiderPreparingStores = true
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(4), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendQuorumDenominator() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+
+	// Scale out store-4, store-5 and store-6, which are still preparing.
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		suite.tc.PutStore(suite.tc.GetStore(storeID).Clone(core.SetStoreState(metapb.StoreState_Up)))
+	}
+	for storeID := uint64(4); storeID <= 6; storeID++ {
+		suite.tc.AddLeaderStore(storeID, 0)
+		re.True(suite.tc.GetStore(storeID).IsPreparing())
+	}
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	es2.conf.ConsiderPreparingStores = false
+	stores := suite.tc.GetStores()
+	re.Len(es2.conf.quorumStores(stores), 6)
+
+	// Store-1 is slower than both of the eligible stores, but the preparing
+	// stores make it short of the quorum of all the stores.
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.False(checkStoreSlowerThanOthers(stores, suite.tc.GetStore(1), false, slowTrendComparisonPairwise, alterEpsilon))
+
+	es2.conf.QuorumDenominator = quorumDenominatorEligible
+	re.Len(es2.conf.quorumStores(stores), 3)
+	re.True(checkStoreSlowerThanOthers(es2.conf.quorumStores(stores), suite.tc.GetStore(1), false, slowTrendComparisonPairwise, alterEpsilon))
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+
+	es2.conf.QuorumDenominator = "unknown"
+	re.Error(es2.conf.validateLocked())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendImpactScore() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.MinImpactScore = 50
+	slowTrend := &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+	normalTrend := &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0.0,
+		ResultValue: 5.0e3,
+		ResultRate:  0.0,
+	}
+
+	// Store-1 holds a few leaders.
+	suite.setStoreSlowTrend(1, slowTrend)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(float64(10), testutil.ToFloat64(storeSlowTrendMiscGauge.WithLabelValues("candidate", "impact_score")))
+
+	// Store-3 holds most of the leaders.
+	suite.setStoreSlowTrend(1, normalTrend)
+	suite.setStoreSlowTrend(3, slowTrend)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(3), es2.conf.candidate())
+	re.Equal(float64(100), testutil.ToFloat64(storeSlowTrendMiscGauge.WithLabelValues("candidate", "impact_score")))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendNoTrendData() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	noTrendData := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_no_trend_data")
+	count := testutil.ToFloat64(noTrendData)
+
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(count, testutil.ToFloat64(noTrendData))
+
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		suite.setStoreSlowTrend(storeID, nil)
+	}
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(count+1, testutil.ToFloat64(noTrendData))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendRecoveryStabilityWindow() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	es2.conf.RecoveryStabilityWindow = 3
+	slowTrend := &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+	normalTrend := &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0.0,
+		ResultValue: 5.0e3,
+		ResultRate:  0.0,
+	}
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	suite.updateStoresHeartbeat(1)
+
+	// Store-1 looks fast briefly, then becomes slow again.
+	for i := 0; i < 2; i++ {
+		suite.es.Schedule(suite.tc, false)
+		re.Equal(uint64(1), es2.conf.evictedStore())
+	}
+	suite.setStoreSlowTrend(1, slowTrend)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+
+	// Store-1 stays fast past the window.
+	suite.setStoreSlowTrend(1, normalTrend)
+	for i := 0; i < 2; i++ {
+		suite.es.Schedule(suite.tc, false)
+		re.Equal(uint64(1), es2.conf.evictedStore())
+	}
+	suite.es.Schedule(suite.tc, false)
+	re.Zero(es2.conf.evictedStore())
+}
+
+type fixedCandidateSelector struct {
+	storeID uint64
+}
+
+func (sel *fixedCandidateSelector) SelectCandidate(cluster sche.SchedulerCluster, _ []*core.StoreInfo) *core.StoreInfo {
+	return cluster.GetStore(sel.storeID)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendCandidateSelector() {
+	re := suite.Require()
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	es := newEvictSlowTrendScheduler(suite.oc, conf, WithEvictSlowTrendCandidateSelector(&fixedCandidateSelector{storeID: 2}))
+
+	// Store-2 is captured by the custom selector though its trend is normal.
+	ops, _ := es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(2), conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendLeadersMovedCounter() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	leadersMoved := storeSlowTrendLeadersMovedCounter.WithLabelValues("1")
+	moved := testutil.ToFloat64(leadersMoved)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	for i := 0; i < 2; i++ {
+		ops, _ := suite.es.Schedule(suite.tc, false)
+		re.NotEmpty(ops)
+		moved += float64(len(ops))
+		re.Equal(moved, testutil.ToFloat64(leadersMoved))
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendScaledRecoveryGap() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	es2.conf.RecoveryDurationGap = 0
+	es2.conf.RecoveryGapScalingFactor = 1
+	es2.conf.MaxRecoveryDurationGap = 7200
+	evict := func() {
+		es2.conf.LastEvictCandidate = slowCandidate{StoreID: 1, CaptureTS: now}
+		re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+		suite.updateStoresHeartbeat(1)
+	}
+
+	// Store-1 was slow for a minute.
+	evict()
+	now = now.Add(time.Minute)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	now = now.Add(time.Minute)
+	suite.es.Schedule(suite.tc, false)
+	re.Zero(es2.conf.evictedStore())
+
+	// Store-1 was slow for an hour.
+	evict()
+	now = now.Add(time.Hour)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	now = now.Add(time.Minute)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	now = now.Add(time.Hour)
+	suite.es.Schedule(suite.tc, false)
+	re.Zero(es2.conf.evictedStore())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendRecoveryProgress() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	es2.conf.RecoveryDurationGap = 600
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	es2.conf.LastEvictCandidate = slowCandidate{StoreID: 1, CaptureTS: now}
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	progress := storeSlowTrendRecoveryProgressGauge.WithLabelValues("1", "progress")
+	faster := storeSlowTrendRecoveryProgressGauge.WithLabelValues("1", "faster")
+
+	// The progress rises as the clock advances, and stops at 1.0.
+	start := now
+	for _, tc := range []struct {
+		elapsed  time.Duration
+		progress float64
+	}{
+		{0, 0},
+		{150 * time.Second, 0.25},
+		{300 * time.Second, 0.5},
+		{600 * time.Second, 1.0},
+		{900 * time.Second, 1.0},
+	} {
+		now = start.Add(tc.elapsed)
+		suite.es.Schedule(suite.tc, false)
+		re.Equal(uint64(1), es2.conf.evictedStore())
+		re.Equal(tc.progress, testutil.ToFloat64(progress))
+		re.Zero(testutil.ToFloat64(faster))
+	}
+
+	// The progress is dropped once the store is not evicted anymore.
+	re.NoError(es2.ClearEvictedStore(suite.tc, 1))
+	re.False(storeSlowTrendRecoveryProgressGauge.DeleteLabelValues("1", "progress"))
+	re.False(storeSlowTrendRecoveryProgressGauge.DeleteLabelValues("1", "faster"))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendEvictionSLABreach() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	es2.conf.RecoveryDurationGap = 3600
+	es2.conf.EvictionSLA = 1800
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	es2.conf.LastEvictCandidate = slowCandidate{StoreID: 1, CaptureTS: now}
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	breach := storeSlowTrendEvictionSLABreach.WithLabelValues("1")
+
+	start := now
+	now = start.Add(1800 * time.Second)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	re.Zero(testutil.ToFloat64(breach))
+	// The gauge flips once the store has been evicted for longer than the SLA.
+	now = start.Add(1801 * time.Second)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	re.Equal(1.0, testutil.ToFloat64(breach))
+
+	// The gauge is reset on recovery.
+	re.NoError(es2.ClearEvictedStore(suite.tc, 1))
+	re.Zero(testutil.ToFloat64(breach))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendPerStoreRecoveryGap() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	es2.conf.RecoveryDurationGap = 600
+	es2.conf.PerStoreRecoveryGap = map[uint64]uint64{2: 1800}
+	start := now
+
+	// Store-1 falls back to the global recovery gap.
+	es2.conf.LastEvictCandidate = slowCandidate{StoreID: 1, CaptureTS: start}
+	now = start.Add(599 * time.Second)
+	re.False(es2.conf.readyForRecovery())
+	now = start.Add(600 * time.Second)
+	re.True(es2.conf.readyForRecovery())
+
+	// Store-2 requires a longer recovery gap.
+	es2.conf.LastEvictCandidate = slowCandidate{StoreID: 2, CaptureTS: start}
+	re.False(es2.conf.readyForRecovery())
+	re.Equal(600.0/1800, es2.conf.recoveryProgress())
+	now = start.Add(1800 * time.Second)
+	re.True(es2.conf.readyForRecovery())
+
+	// The overrides are persisted.
+	es2.conf.Lock()
+	re.NoError(es2.conf.persistLocked())
+	es2.conf.Unlock()
+	var persisted evictSlowTrendSchedulerConfig
+	_, values, err := es2.conf.storage.LoadAllSchedulerConfigs()
+	re.NoError(err)
+	re.Len(values, 1)
+	re.NoError(json.Unmarshal([]byte(values[0]), &persisted))
+	re.Equal(map[uint64]uint64{2: 1800}, persisted.PerStoreRecoveryGap)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendFlapping() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	es2.conf.RecoveryDurationGap = 0
+	es2.conf.FlapThreshold = 3
+	es2.conf.FlapWindow = 3600
+	es2.conf.FlapHoldDuration = 1800
+	evict := func() {
+		es2.conf.LastEvictCandidate = slowCandidate{StoreID: 1, CaptureTS: now}
+		re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+		suite.updateStoresHeartbeat(1)
+	}
+
+	// Store-1 recovers as usual in the first cycles.
+	count := testutil.ToFloat64(evictSlowTrendFlappingCounter)
+	for i := 0; i < 2; i++ {
+		evict()
+		now = now.Add(time.Minute)
+		suite.es.Schedule(suite.tc, false)
+		re.Zero(es2.conf.evictedStore())
+	}
+	re.Equal(count, testutil.ToFloat64(evictSlowTrendFlappingCounter))
+
+	// It's flapping, hold it evicted for the extended duration.
+	evict()
+	re.Equal(count+1, testutil.ToFloat64(evictSlowTrendFlappingCounter))
+	now = now.Add(time.Minute)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	now = now.Add(25 * time.Minute)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	now = now.Add(5 * time.Minute)
+	suite.es.Schedule(suite.tc, false)
+	re.Zero(es2.conf.evictedStore())
+
+	// The evictions out of the window are not counted.
+	now = now.Add(time.Hour)
+	evict()
+	re.Equal(count+1, testutil.ToFloat64(evictSlowTrendFlappingCounter))
+	now = now.Add(time.Minute)
+	suite.es.Schedule(suite.tc, false)
+	re.Zero(es2.conf.evictedStore())
+
+	es2.conf.FlapWindow = 0
+	re.Error(es2.conf.contradiction())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendReconcileCandidate() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	es2.conf.EvictCandidate = slowCandidate{StoreID: 1, CaptureTS: time.Now(), RecoverTS: time.Now()}
+
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	state := es2.conf.state()
+	re.Zero(state.EvictCandidate.StoreID)
+	re.Equal([]uint64{1}, state.EvictedStores)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendMinUsedSpaceRatio() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.MinUsedSpaceRatio = 0.8
+	spaceOK := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_space_ok")
+	skipped := testutil.ToFloat64(spaceOK)
+	suite.tc.UpdateStorageRatio(1, 0.2, 0.8)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+
+	// Store-1 is slow but has enough free space.
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(skipped+1, testutil.ToFloat64(spaceOK))
+
+	// Store-1 is nearly full.
+	suite.tc.UpdateStorageRatio(1, 0.9, 0.1)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendWarmup() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.WarmupDuration = 300
+	warmingUp := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_warming_up")
+	skipped := testutil.ToFloat64(warmingUp)
+	storeInfo := suite.tc.GetStore(1)
+	startTS := storeInfo.GetLastHeartbeatTS().Add(-time.Minute).Unix()
+	suite.tc.PutStore(storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
+			CauseValue:  5.0e8,
+			CauseRate:   1e7,
+			ResultValue: 3.0e3,
+			ResultRate:  -1e7,
+		}
+	}, core.SetStoreStartTime(startTS)))
+
+	// Store-1 is just restarted.
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(skipped+1, testutil.ToFloat64(warmingUp))
+
+	// Store-1 has been up for longer than the warmup duration.
+	storeInfo = suite.tc.GetStore(1)
+	suite.tc.PutStore(storeInfo.Clone(core.SetLastHeartbeatTS(storeInfo.GetLastHeartbeatTS().Add(5 * time.Minute))))
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendLastError() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	re.Nil(es2.LastError())
+
+	persistFail := "github.com/tikv/pd/pkg/schedule/schedulers/persistFail"
+	re.NoError(failpoint.Enable(persistFail, "return(true)"))
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	lastErr := es2.LastError()
+	re.NotNil(lastErr)
+	re.ErrorContains(lastErr.Err, "fail to persist")
+	re.Equal(now, lastErr.TS)
+	re.NoError(failpoint.Disable(persistFail))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendPersistFailurePolicy() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	persistedStores := func() []uint64 {
+		data, err := es2.conf.storage.LoadSchedulerConfig(EvictSlowTrendName)
+		re.NoError(err)
+		if len(data) == 0 {
+			return nil
+		}
+		conf := &evictSlowTrendSchedulerConfig{}
+		re.NoError(DecodeConfig([]byte(data), conf))
+		return conf.EvictedStores
+	}
+	persistFail := "github.com/tikv/pd/pkg/schedule/schedulers/persistFail"
+
+	// The decisions are kept and persisted again in the next schedule.
+	re.Equal(persistFailurePolicyRetry, es2.conf.PersistFailurePolicy)
+	re.NoError(failpoint.Enable(persistFail, "return(true)"))
+	re.Error(es2.conf.setStoreAndPersist(1))
+	re.Equal([]uint64{1}, es2.conf.getStores())
+	suite.es.Schedule(suite.tc, false)
+	re.NotNil(es2.LastError())
+	re.Empty(persistedStores())
+	re.NoError(failpoint.Disable(persistFail))
+	suite.es.Schedule(suite.tc, false)
+	re.Equal([]uint64{1}, persistedStores())
+	re.False(es2.conf.persistPending)
+
+	// The decisions are rolled back to match the storage.
+	es2.conf.PersistFailurePolicy = persistFailurePolicyRollback
+	re.NoError(failpoint.Enable(persistFail, "return(true)"))
+	es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	re.Equal([]uint64{1}, es2.conf.getStores())
+	re.NoError(failpoint.Disable(persistFail))
+	es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	re.Empty(es2.conf.getStores())
+	re.Empty(persistedStores())
+	recentEvictions := es2.conf.RecentEvictions
+	re.NoError(failpoint.Enable(persistFail, "return(true)"))
+	re.Error(es2.conf.setStoreAndPersist(2))
+	re.Empty(es2.conf.getStores())
+	re.Equal(recentEvictions, es2.conf.RecentEvictions)
+	re.False(es2.conf.persistPending)
+	re.NoError(failpoint.Disable(persistFail))
+
+	// Every persisted decision honors the policy, including the annotations
+	// and resuming the expired pause.
+	re.NoError(es2.conf.setStoreAndPersist(2))
+	re.NoError(es2.conf.pause(time.Minute))
+	re.NoError(failpoint.Enable(persistFail, "return(true)"))
+	_, err := es2.conf.annotateAndPersist(2, "disk")
+	re.Error(err)
+	re.Empty(es2.conf.evictedReason(2))
+	re.Error(es2.conf.resume())
+	re.False(es2.conf.PausedUntil.IsZero())
+	es2.conf.PersistFailurePolicy = persistFailurePolicyRetry
+	_, err = es2.conf.annotateAndPersist(2, "disk")
+	re.Error(err)
+	re.Equal("disk", es2.conf.evictedReason(2))
+	re.Error(es2.conf.resume())
+	re.True(es2.conf.PausedUntil.IsZero())
+	re.True(es2.conf.persistPending)
+	re.NoError(failpoint.Disable(persistFail))
+	re.NoError(es2.conf.retryPersist())
+	data, err := es2.conf.storage.LoadSchedulerConfig(EvictSlowTrendName)
+	re.NoError(err)
+	persisted := &evictSlowTrendSchedulerConfig{}
+	re.NoError(DecodeConfig([]byte(data), persisted))
+	re.Equal("disk", persisted.EvictedReasons[2])
+	re.True(persisted.PausedUntil.IsZero())
+
+	es2.conf.PersistFailurePolicy = "ignore"
+	re.Error(es2.conf.validateLocked())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendEvictionCounts() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	suite.tc.AddLeaderStore(4, 10)
+	evict := func(storeID uint64) {
+		re.NoError(es2.prepareEvictLeader(suite.tc, storeID))
+		es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	}
+	evict(4)
+	for i := 0; i < 3; i++ {
+		evict(1)
+	}
+	re.Equal(map[uint64]uint64{1: 3, 4: 1}, es2.conf.Clone().EvictionCounts)
+
+	// The count of the removed store is pruned in the next eviction.
+	suite.tc.PutStore(suite.tc.GetStore(4).Clone(core.SetStoreState(metapb.StoreState_Tombstone)))
+	evict(1)
+	re.Equal(map[uint64]uint64{1: 4}, es2.conf.Clone().EvictionCounts)
+
+	// The counts survive the reloading.
+	sche, err := CreateScheduler(EvictSlowTrendType, suite.oc, es2.conf.storage, ConfigSliceDecoder(EvictSlowTrendType, []string{}))
+	re.NoError(err)
+	re.NoError(sche.ReloadConfig())
+	re.Equal(map[uint64]uint64{1: 4}, sche.(*evictSlowTrendScheduler).conf.Clone().EvictionCounts)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendMinClusterLeaderCount() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	tooFewLeaders := storeSlowTrendActionStatusGauge.WithLabelValues("candidate", "none_too_few_leaders")
+	skipped := testutil.ToFloat64(tooFewLeaders)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+
+	// There are only 209 leaders in the cluster.
+	es2.conf.MinClusterLeaderCount = 1000
+	for i := 0; i < 3; i++ {
+		ops, _ := suite.es.Schedule(suite.tc, false)
+		re.Empty(ops)
+		re.Zero(es2.conf.candidate())
+		re.Zero(es2.conf.evictedStore())
+	}
+	re.Equal(skipped+3, testutil.ToFloat64(tooFewLeaders))
+
+	es2.conf.MinClusterLeaderCount = 200
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendState() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	getState := func() string {
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1/state", http.NoBody)
+		re.NoError(err)
+		resp := httptest.NewRecorder()
+		suite.es.ServeHTTP(resp, req)
+		re.Equal(http.StatusOK, resp.Code)
+		var state map[string]string
+		re.NoError(json.Unmarshal(resp.Body.Bytes(), &state))
+		re.Equal(es2.State(), state["state"])
+		return state["state"]
+	}
+	re.Equal(slowTrendStateIdle, getState())
+
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(slowTrendStateCandidatePending, getState())
+
+	suite.updateStoresHeartbeat(2, 3)
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(slowTrendStateEvicting, getState())
+
+	storeInfo := suite.tc.GetStore(1)
+	suite.tc.PutStore(storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0.0,
+			ResultValue: 5.0e3,
+			ResultRate:  0.0,
+		}
+	}, core.SetLastHeartbeatTS(time.Now())))
+	suite.es.Schedule(suite.tc, false)
+	re.Equal(slowTrendStateIdle, getState())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendSkipRecoveredOnRemoval() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	for _, skip := range []bool{false, true} {
+		es2.conf.SkipRecoveredOnRemoval = skip
+		re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+		re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+		es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRemoved)
+		re.Zero(es2.conf.evictedStore())
+		// The store is not notified as recovered on the removal path.
+		re.Equal(skip, suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+		suite.tc.SlowTrendRecovered(1)
+	}
+
+	// It's always notified on the recovery path.
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+	re.False(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendConfidence() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.ConfidenceThreshold = 3
+	slow := &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+	normal := &pdpb.SlowTrend{
+		CauseValue:  5.0e6,
+		CauseRate:   0.0,
+		ResultValue: 5.0e3,
+		ResultRate:  0.0,
+	}
+	// Store-1 matches the pattern intermittently.
+	for i, slowTrend := range []*pdpb.SlowTrend{slow, normal, slow, normal, slow, slow} {
+		suite.setStoreSlowTrend(1, slowTrend)
+		ops, _ := suite.es.Schedule(suite.tc, false)
+		re.Empty(ops)
+		re.Zero(es2.conf.candidate(), i)
+	}
+	re.Equal(2.0, es2.conf.Confidences[1])
+
+	// Store-1 keeps matching the pattern.
+	suite.setStoreSlowTrend(1, slow)
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal(uint64(1), es2.conf.evictedStore())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendStateDuration() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	now := time.Now()
+	es2.conf.now = func() time.Time { return now }
+	durations := make(map[string]float64)
+	for _, state := range []string{slowTrendStateIdle, slowTrendStateCandidatePending, slowTrendStateEvicting} {
+		durations[state] = testutil.ToFloat64(storeSlowTrendStateDurationCounter.WithLabelValues(state))
+	}
+	tick := func(elapsed time.Duration) {
+		now = now.Add(elapsed)
+		suite.es.Schedule(suite.tc, false)
+	}
+
+	// The first tick only records the time.
+	tick(0)
+	tick(10 * time.Second)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	tick(20 * time.Second)
+	re.Equal(slowTrendStateCandidatePending, es2.State())
+	suite.updateStoresHeartbeat(2, 3)
+	tick(30 * time.Second)
+	re.Equal(slowTrendStateEvicting, es2.State())
+	tick(40 * time.Second)
+	tick(50 * time.Second)
+
+	expected := map[string]float64{
+		slowTrendStateIdle:             30,
+		slowTrendStateCandidatePending: 30,
+		slowTrendStateEvicting:         90,
+	}
+	var total float64
+	for state, duration := range expected {
+		re.InDelta(durations[state]+duration, testutil.ToFloat64(storeSlowTrendStateDurationCounter.WithLabelValues(state)), 1e-6, state)
+		total += duration
+	}
+	re.Equal(150.0, total)
+}
+
+func TestCompareSlowTrendSeverity(t *testing.T) {
+	re := require.New(t)
+	newStore := func(id uint64, slowTrend *pdpb.SlowTrend) *core.StoreInfo {
+		store := core.NewStoreInfo(&metapb.Store{Id: id})
+		return store.Clone(core.SetStoreStats(&pdpb.StoreStats{StoreId: id, SlowTrend: slowTrend}))
+	}
+	stores := []*core.StoreInfo{
+		newStore(1, nil),
+		newStore(2, &pdpb.SlowTrend{CauseValue: 5.0e8, CauseRate: 1e7, ResultValue: 3.0e3, ResultRate: -1e7}),
+		newStore(3, &pdpb.SlowTrend{CauseValue: 5.0e8, CauseRate: 2e7, ResultValue: 3.0e3, ResultRate: -1e7}),
+		newStore(4, &pdpb.SlowTrend{CauseValue: 6.0e8, CauseRate: 1e6, ResultValue: 5.0e3, ResultRate: -1e6}),
+		newStore(5, &pdpb.SlowTrend{CauseValue: 5.0e8, CauseRate: 1e7, ResultValue: 3.0e3, ResultRate: -2e7}),
+		newStore(6, &pdpb.SlowTrend{CauseValue: 5.0e8, CauseRate: 1e7, ResultValue: 2.0e3, ResultRate: -1e7}),
+		newStore(7, &pdpb.SlowTrend{CauseValue: 5.0e8, CauseRate: 1e7, ResultValue: 3.0e3, ResultRate: -1e7}),
+	}
+	expected := []uint64{4, 3, 5, 6, 2, 7, 1}
+	for i := 0; i < 10; i++ {
+		rand.Shuffle(len(stores), func(i, j int) { stores[i], stores[j] = stores[j], stores[i] })
+		sort.Slice(stores, func(i, j int) bool {
+			return compareSlowTrendSeverity(stores[i], stores[j]) < 0
+		})
+		ids := make([]uint64, 0, len(stores))
+		for _, store := range stores {
+			ids = append(ids, store.GetID())
+		}
+		re.Equal(expected, ids)
+	}
+}
+
+func TestCheckStoreSlowerThanMedian(t *testing.T) {
+	re := require.New(t)
+	newStore := func(id uint64, causeValue float64) *core.StoreInfo {
+		store := core.NewStoreInfo(&metapb.Store{Id: id, NodeState: metapb.NodeState_Serving})
+		return store.Clone(core.SetStoreStats(&pdpb.StoreStats{
+			StoreId:   id,
+			SlowTrend: &pdpb.SlowTrend{CauseValue: causeValue},
+		}))
+	}
+	// Store 1 is slow, stores 6 and 7 are outliers which are even slower.
+	stores := []*core.StoreInfo{
+		newStore(1, 5.0e8),
+		newStore(2, 5.0e6),
+		newStore(3, 5.0e6),
+		newStore(4, 5.0e6),
+		newStore(5, 5.0e6),
+		newStore(6, 1.0e9),
+		newStore(7, 1.0e9),
+	}
+	re.False(checkStoreSlowerThanOthers(stores, stores[0], false, slowTrendComparisonPairwise, alterEpsilon))
+	re.True(checkStoreSlowerThanOthers(stores, stores[0], false, slowTrendComparisonMedian, alterEpsilon))
+	// A normal store is not slower than the median.
+	re.False(checkStoreSlowerThanOthers(stores, stores[1], false, slowTrendComparisonMedian, alterEpsilon))
+	// The empty method is regarded as pairwise.
+	re.False(checkStoreSlowerThanOthers(stores, stores[0], false, "", alterEpsilon))
+
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	re.Equal(slowTrendComparisonPairwise, conf.ComparisonMethod)
+	conf.ComparisonMethod = "mean"
+	re.Error(conf.validateLocked())
+	conf.ComparisonMethod = slowTrendComparisonMedian
+	re.NoError(conf.validateLocked())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendPickWorstCandidate() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	suite.setStoreSlowTrend(2, &pdpb.SlowTrend{
+		CauseValue:  6.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	es2.conf.PickWorstCandidate = true
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(2), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendTiedCandidates() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	slowTrend := &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	}
+	suite.setStoreSlowTrend(2, slowTrend)
+	suite.setStoreSlowTrend(1, slowTrend)
+	// Compare with the median, as the tied stores are not slower than each other.
+	for _, storeID := range []uint64{4, 5} {
+		suite.tc.AddLeaderStore(storeID, 100)
+		suite.setStoreSlowTrend(storeID, &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0.0,
+			ResultValue: 5.0e3,
+			ResultRate:  0.0,
+		})
+	}
+	es2.conf.ComparisonMethod = slowTrendComparisonMedian
+	es2.conf.PickWorstCandidate = true
+	// The lower store ID is chosen regardless of the order of the stores.
+	stores := suite.tc.GetStores()
+	for i := 0; i < 10; i++ {
+		rand.Shuffle(len(stores), func(i, j int) { stores[i], stores[j] = stores[j], stores[i] })
+		store := chooseEvictCandidate(suite.tc, stores, es2.conf, nil)
+		re.NotNil(store)
+		re.Equal(uint64(1), store.GetID())
+	}
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendTailLatency() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	setTailLatency := func(storeID, tailLatency uint64) {
+		storeInfo := suite.tc.GetStore(storeID)
+		suite.tc.PutStore(storeInfo.Clone(func(store *core.StoreInfo) {
+			store.GetStoreStats().OpLatencies = []*pdpb.RecordPair{{Key: tailLatencyRecordKey, Value: tailLatency}}
+		}))
+	}
+	setTailLatency(1, 500000)
+	setTailLatency(2, 1000)
+
+	// The tail latency is ignored without the flag.
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+
+	es2.conf.TailLatencyThreshold = 100000
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	es2.conf.popCandidate(false)
+
+	// It's a no-op if the stat is absent.
+	storeInfo := suite.tc.GetStore(1)
+	suite.tc.PutStore(storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().OpLatencies = nil
+	}))
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendContradictoryConfig() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	postConfig := func(input map[string]any) (int, string) {
+		data, err := json.Marshal(input)
+		re.NoError(err)
+		req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1/config", strings.NewReader(string(data)))
+		re.NoError(err)
+		resp := httptest.NewRecorder()
+		suite.es.ServeHTTP(resp, req)
+		return resp.Code, resp.Body.String()
+	}
+	testCases := []struct {
+		input    map[string]any
+		errorMsg string
+	}{
+		{map[string]any{"max-recovery-duration": 600}, "recovery-gap-scaling-factor"},
+		{map[string]any{"max-evictions-per-window": 1, "eviction-budget-window": 0}, "eviction-budget-window"},
+		{map[string]any{"comparison-method": "median", "detection-mode": "longitudinal"}, "longitudinal"},
+		{map[string]any{"maintenance-window-start": "01:00", "maintenance-window-end": "01:00"}, "maintenance window"},
+	}
+	for _, tc := range testCases {
+		code, msg := postConfig(tc.input)
+		re.Equal(http.StatusBadRequest, code, tc.input)
+		re.Contains(msg, tc.errorMsg)
+	}
+	cfg := es2.conf.Clone()
+	re.Zero(cfg.MaxRecoveryDurationGap)
+	re.Equal(slowTrendDetectionModeCrossSectional, cfg.DetectionMode)
+	// The items can be set together.
+	code, _ := postConfig(map[string]any{"max-recovery-duration": 600, "recovery-gap-scaling-factor": 2})
+	re.Equal(http.StatusOK, code)
+
+	// The evicted store still recovers if the contradictory config is loaded.
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	re.Equal(uint64(1), es2.conf.evictedStore())
+	skipped := testutil.ToFloat64(evictSlowTrendContradictoryCounter)
+	es2.conf.RecoveryGapScalingFactor = 0
+	re.True(suite.es.IsScheduleAllowed(suite.tc))
+	suite.updateStoresHeartbeat(1)
+	suite.es.Schedule(suite.tc, false)
+	re.Zero(es2.conf.evictedStore())
+	re.False(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+
+	// But no slow store is captured.
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(es2.conf.candidate())
+	re.Equal(skipped+1, testutil.ToFloat64(evictSlowTrendContradictoryCounter))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendTruncatedConfigBody() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	prev := es2.conf.Clone().RecoveryDurationGap
+	// The body is truncated by a broken connection, the partial JSON is not
+	// applied.
+	body := io.MultiReader(strings.NewReader(`{"recovery-duration": 1`), iotest.ErrReader(errors.New("unexpected EOF")))
+	req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1/config", body)
+	re.NoError(err)
+	resp := httptest.NewRecorder()
+	suite.es.ServeHTTP(resp, req)
+	re.Equal(http.StatusBadRequest, resp.Code)
+	re.Contains(resp.Body.String(), "unexpected EOF")
+	re.Equal(prev, es2.conf.Clone().RecoveryDurationGap)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendMaintenanceWindow() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	re.NoError(err)
+	// Tomorrow, so that the candidate is captured after the heartbeats.
+	year, month, day := time.Now().In(loc).AddDate(0, 0, 1).Date()
+	now := time.Date(year, month, day, 2, 0, 0, 0, loc)
+	es2.conf.now = func() time.Time { return now }
+
+	// Invalid windows are rejected.
+	postConfig := func(input map[string]any) int {
+		data, err := json.Marshal(input)
+		re.NoError(err)
+		req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1/config", strings.NewReader(string(data)))
+		re.NoError(err)
+		resp := httptest.NewRecorder()
+		suite.es.ServeHTTP(resp, req)
+		return resp.Code
+	}
+	re.Equal(http.StatusBadRequest, postConfig(map[string]any{"maintenance-window-start": "25:00", "maintenance-window-end": "05:00"}))
+	re.Equal(http.StatusBadRequest, postConfig(map[string]any{"maintenance-window-start": "23:00"}))
+	re.Equal(http.StatusBadRequest, postConfig(map[string]any{
+		"maintenance-window-start": "23:00", "maintenance-window-end": "05:00", "maintenance-window-timezone": "Mars/Olympus",
+	}))
+	re.Empty(es2.conf.Clone().MaintenanceWindowStart)
+	// The window crosses midnight in Asia/Shanghai.
+	re.Equal(http.StatusOK, postConfig(map[string]any{
+		"maintenance-window-start": "23:00", "maintenance-window-end": "05:00", "maintenance-window-timezone": "Asia/Shanghai",
+	}))
+
+	skipped := testutil.ToFloat64(evictSlowTrendMaintenanceCounter)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	// 02:00 in Asia/Shanghai, and it's 18:00 in UTC.
+	for _, ts := range []time.Time{now, now.UTC()} {
+		now = ts
+		ops, _ := suite.es.Schedule(suite.tc, false)
+		re.Empty(ops)
+		re.Zero(es2.conf.candidate())
+	}
+	re.Equal(skipped+2, testutil.ToFloat64(evictSlowTrendMaintenanceCounter))
+
+	// The existing eviction is kept in the window.
+	re.NoError(es2.prepareEvictLeader(suite.tc, 2))
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(2), es2.conf.evictedStore())
+	es2.cleanupEvictLeader(suite.tc, evictCleanupReasonRecovered)
+
+	// 06:00 in Asia/Shanghai is out of the window.
+	now = time.Date(year, month, day, 6, 0, 0, 0, loc)
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(1), es2.conf.candidate())
+	re.Equal(skipped+3, testutil.ToFloat64(evictSlowTrendMaintenanceCounter))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendPrepareConfig() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	es2.conf.EvictedStores = []uint64{1, 100, 2}
+	// Store-2 has been evicted already, it should be idempotent.
+	re.NoError(suite.tc.SlowTrendEvicted(2))
+
+	// Store-100 does not exist, but the others are still re-applied.
+	err := suite.es.PrepareConfig(suite.tc)
+	re.Error(err)
+	re.NotNil(es2.LastError())
+	re.True(suite.tc.GetStore(1).IsEvictedAsSlowTrend())
+	re.True(suite.tc.GetStore(2).IsEvictedAsSlowTrend())
+	re.False(suite.tc.GetStore(3).IsEvictedAsSlowTrend())
+
+	es2.conf.EvictedStores = []uint64{1, 2}
+	re.NoError(suite.es.PrepareConfig(suite.tc))
+	suite.es.CleanConfig(suite.tc)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendOperatorTimeout() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	re.NoError(es2.prepareEvictLeader(suite.tc, 1))
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	defaultTimeout := ops[0].GetTimeout()
+	re.Positive(defaultTimeout)
+
+	es2.conf.EvictOperatorTimeout = 3
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	for _, op := range ops {
+		re.Equal(3*time.Second, op.GetTimeout())
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendListScan() {
+	re := suite.Require()
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	suite.es.Schedule(suite.tc, false)
+
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1/scan", http.NoBody)
+	re.NoError(err)
+	resp := httptest.NewRecorder()
+	suite.es.ServeHTTP(resp, req)
+	re.Equal(http.StatusOK, resp.Code)
+	var scan slowTrendScan
+	re.NoError(json.Unmarshal(resp.Body.Bytes(), &scan))
+	re.False(scan.ScanTS.IsZero())
+	re.Len(scan.Stores, 3)
+	for _, result := range scan.Stores {
+		if result.StoreID == 1 {
+			re.True(result.Matched)
+			re.Equal(5.0e8, result.CauseValue)
+			re.Equal(1e7, result.CauseRate)
+			re.Equal(3.0e3, result.ResultValue)
+			re.Equal(-1e7, result.ResultRate)
+			re.Equal(2, result.SlowerThanCount)
+			re.Zero(result.FasterThanCount)
+			continue
+		}
+		re.False(result.Matched)
+		re.Equal(5.0e6, result.CauseValue)
+		re.Zero(result.SlowerThanCount)
+		re.Equal(2, result.FasterThanCount)
+	}
+}
+
+func TestEvictSlowTrendScanComparisonEpsilon(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1, withTestSlowTrend(slowSlowTrend())).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	stores := c.GetStores()
+	// The scan counts the same as the scheduler decides under each epsilon.
+	for _, epsilon := range []float64{1e-12, alterEpsilon, 1e-3, 1e7} {
+		es.conf.ComparisonEpsilon = epsilon
+		es.conf.recordScan(stores)
+		scan := es.conf.lastScan
+		re.Len(scan.Stores, 3)
+		for _, result := range scan.Stores {
+			store := c.GetStore(result.StoreID)
+			re.Equal(countSlowerThanStores(stores, store, false, epsilon), result.SlowerThanCount)
+			re.Equal(countFasterThanStores(stores, store, false, epsilon), result.FasterThanCount)
+		}
+	}
+	// The normal stores are not counted under the large epsilon.
+	for _, result := range es.conf.lastScan.Stores {
+		re.Zero(result.SlowerThanCount)
+		re.Zero(result.FasterThanCount)
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendScheduleDurationHistogram() {
+	re := suite.Require()
+	observed := func() (uint64, float64) {
+		var m dto.Metric
+		re.NoError(storeSlowTrendScheduleDurationHistogram.Write(&m))
+		return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+	}
+	count, sum := observed()
+	for i := 1; i <= 3; i++ {
+		suite.es.Schedule(suite.tc, false)
+		newCount, newSum := observed()
+		re.Equal(count+uint64(i), newCount)
+		re.Greater(newSum, sum)
+		sum = newSum
+	}
+}
+
+func newSlowTrendBenchCluster(storeCount int) (context.CancelFunc, *mockcluster.Cluster, *operator.Controller) {
+	cancel, _, tc, oc := prepareSchedulersTest()
+	now := time.Now()
+	for i := 1; i <= storeCount; i++ {
+		storeID := uint64(i)
+		tc.AddLeaderStore(storeID, 10)
+		// The QPS of other stores drops too since they are affected by the
+		// slow store.
+		slowTrend := &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0.0,
+			ResultValue: 5.0e3,
+			ResultRate:  -1e7,
+		}
+		if storeID == 3 {
+			slowTrend = &pdpb.SlowTrend{
+				CauseValue:  5.0e8,
+				CauseRate:   1e7,
+				ResultValue: 3.0e3,
+				ResultRate:  -1e7,
+			}
+		}
+		store := tc.GetStore(storeID)
+		tc.PutStore(store.Clone(func(store *core.StoreInfo) {
+			store.GetStoreStats().SlowTrend = slowTrend
+		}, core.SetLastHeartbeatTS(now)))
+	}
+	return cancel, tc, oc
+}
+
+func TestEvictSlowTrendSelectionWithStoresSnapshot(t *testing.T) {
+	re := require.New(t)
+	cancel, tc, oc := newSlowTrendBenchCluster(10)
+	defer cancel()
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	stores := tc.GetStores()
+	candidate := chooseEvictCandidate(tc, stores, conf, nil)
+	re.NotNil(candidate)
+	re.Equal(uint64(3), candidate.GetID())
+	re.True(checkStoreSlowerThanOthers(stores, candidate, true, slowTrendComparisonPairwise, alterEpsilon))
+	re.False(checkStoreFasterThanOthers(stores, candidate, true, alterEpsilon))
+
+	// The scheduler fetching the stores once per tick captures the same store.
+	es := newEvictSlowTrendScheduler(oc, conf)
+	ops, _ := es.Schedule(tc, false)
+	re.Empty(ops)
+	re.Equal(uint64(3), conf.candidate())
+}
+
+func BenchmarkEvictSlowTrendSchedule(b *testing.B) {
+	cancel, tc, oc := newSlowTrendBenchCluster(1000)
+	defer cancel()
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	es := newEvictSlowTrendScheduler(oc, conf)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		es.Schedule(tc, false)
+		// Drop the candidate so that every tick scans all stores again.
+		conf.popCandidate(false)
+	}
+}
+
+type memoryEvictionSpan struct {
+	storeID uint64
+	events  []string
+	ended   bool
+}
+
+func (span *memoryEvictionSpan) AddEvent(name string) {
+	span.events = append(span.events, name)
+}
+
+func (span *memoryEvictionSpan) End() {
+	span.ended = true
+}
+
+type memoryEvictionTracer struct {
+	spans []*memoryEvictionSpan
+}
+
+func (tracer *memoryEvictionTracer) StartSpan(_ string, storeID uint64) EvictionSpan {
+	span := &memoryEvictionSpan{storeID: storeID}
+	tracer.spans = append(tracer.spans, span)
+	return span
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendTracer() {
+	re := suite.Require()
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	tracer := &memoryEvictionTracer{}
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	es := newEvictSlowTrendScheduler(suite.oc, conf, WithEvictSlowTrendTracer(tracer))
+
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Len(tracer.spans, 1)
+	span := tracer.spans[0]
+	re.Equal(uint64(1), span.storeID)
+	re.Equal([]string{"captured"}, span.events)
+	re.False(span.ended)
+
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	re.Equal([]string{"captured", "evicted"}, span.events)
+	re.False(span.ended)
+
+	storeInfo := suite.tc.GetStore(1)
+	suite.tc.PutStore(storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0.0,
+			ResultValue: 5.0e3,
+			ResultRate:  0.0,
+		}
+	}, core.SetLastHeartbeatTS(time.Now())))
+	ops, _ = es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	re.Zero(conf.evictedStore())
+	re.Equal([]string{"captured", "evicted", "recovered"}, span.events)
+	re.True(span.ended)
+	re.Len(tracer.spans, 1)
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendWebhook() {
+	re := suite.Require()
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+	received := make(chan SlowTrendEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event SlowTrendEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	conf.WebhookURL = server.URL
+	es := newEvictSlowTrendScheduler(suite.oc, conf)
+	defer es.CleanConfig(suite.tc)
+	checkEvent := func(expected string) {
+		select {
+		case event := <-received:
+			re.Equal(EvictSlowTrendName, event.Scheduler)
+			re.Equal(uint64(1), event.StoreID)
+			re.Equal(expected, event.Event)
+		case <-time.After(5 * time.Second):
+			re.FailNow("webhook event is not received", expected)
+		}
+	}
+
+	suite.setStoreSlowTrend(1, &pdpb.SlowTrend{
+		CauseValue:  5.0e8,
+		CauseRate:   1e7,
+		ResultValue: 3.0e3,
+		ResultRate:  -1e7,
+	})
+	ops, _ := es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	checkEvent("captured")
+	suite.updateStoresHeartbeat(2, 3)
+	ops, _ = es.Schedule(suite.tc, false)
+	re.NotEmpty(ops)
+	checkEvent("evicted")
+	storeInfo := suite.tc.GetStore(1)
+	suite.tc.PutStore(storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowTrend = &pdpb.SlowTrend{
+			CauseValue:  5.0e6,
+			CauseRate:   0.0,
+			ResultValue: 5.0e3,
+			ResultRate:  0.0,
+		}
+	}, core.SetLastHeartbeatTS(time.Now())))
+	ops, _ = es.Schedule(suite.tc, false)
+	re.Empty(ops)
+	checkEvent("recovered")
+}
+
+func TestWebhookNotifierDropsEventsWhenFull(t *testing.T) {
+	re := require.New(t)
+	conf := initEvictSlowTrendSchedulerConfig(storage.NewStorageWithMemoryBackend())
+	n := newWebhookNotifier(conf)
+	defer n.stop()
+	// Nothing is queued without the webhook.
+	n.notify(1, "captured")
+	re.Empty(n.events)
+
+	conf.WebhookURL = "http://127.0.0.1:0"
+	// Do not start the worker, so that the queue gets full.
+	n.startOnce.Do(func() {})
+	dropped := testutil.ToFloat64(slowTrendWebhookDroppedCounter)
+	for i := 0; i < webhookQueueSize+3; i++ {
+		n.notify(1, "captured")
+	}
+	re.Len(n.events, webhookQueueSize)
+	re.Equal(dropped+3, testutil.ToFloat64(slowTrendWebhookDroppedCounter))
+}
+
+func (suite *evictSlowTrendTestSuite) TestReplaySlowTrendTimeline() {
+	re := suite.Require()
+	normal := &pdpb.SlowTrend{CauseValue: 5.0e6, CauseRate: 0.0, ResultValue: 5.0e3, ResultRate: 0.0}
+	slow := &pdpb.SlowTrend{CauseValue: 5.0e8, CauseRate: 1e7, ResultValue: 3.0e3, ResultRate: -1e7}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var timeline []SlowTrendSnapshot
+	// Store-1 is slow from the 1st minute to the 3rd minute.
+	for i := 0; i <= 15; i++ {
+		trend := normal
+		if i >= 1 && i <= 3 {
+			trend = slow
+		}
+		timeline = append(timeline, SlowTrendSnapshot{
+			TS:         start.Add(time.Duration(i) * time.Minute),
+			SlowTrends: map[uint64]*pdpb.SlowTrend{1: trend, 2: normal, 3: normal},
+		})
+	}
+
+	decisions, err := ReplaySlowTrendTimeline(suite.tc, suite.oc, []byte(`{"recovery-duration": 600}`), timeline)
+	re.NoError(err)
+	re.Equal([]SlowTrendDecision{
+		{TS: start.Add(time.Minute), StoreID: 1, Event: "captured"},
+		{TS: start.Add(2 * time.Minute), StoreID: 1, Event: "evicted"},
+		// The store is recovered once the recovery gap since capturing it elapses.
+		{TS: start.Add(11 * time.Minute), StoreID: 1, Event: "recovered"},
+	}, decisions)
+
+	// The confirmation samples are taken by the replayed clock too, the second
+	// sample is not due until 2 minutes after capturing the store.
+	decisions, err = ReplaySlowTrendTimeline(suite.tc, suite.oc,
+		[]byte(`{"recovery-duration": 600, "confirmation-samples": 2, "confirmation-interval": 120}`), timeline)
+	re.NoError(err)
+	re.Equal([]SlowTrendDecision{
+		{TS: start.Add(time.Minute), StoreID: 1, Event: "captured"},
+		{TS: start.Add(3 * time.Minute), StoreID: 1, Event: "evicted"},
+		{TS: start.Add(11 * time.Minute), StoreID: 1, Event: "recovered"},
+	}, decisions)
+
+	// A longer recovery gap keeps the store evicted until the end of the timeline.
+	decisions, err = ReplaySlowTrendTimeline(suite.tc, suite.oc, []byte(`{"recovery-duration": 3600}`), timeline)
+	re.NoError(err)
+	re.Len(decisions, 2)
+	re.Equal("evicted", decisions[1].Event)
+
+	_, err = ReplaySlowTrendTimeline(suite.tc, suite.oc, nil, []SlowTrendSnapshot{{TS: start, SlowTrends: map[uint64]*pdpb.SlowTrend{4: normal}}})
+	re.Error(err)
+}
+
+func (suite *evictSlowTrendTestSuite) TestReplaySlowTrendScaledRecoveryGap() {
+	re := suite.Require()
+	normal := &pdpb.SlowTrend{CauseValue: 5.0e6, CauseRate: 0.0, ResultValue: 5.0e3, ResultRate: 0.0}
+	slow := &pdpb.SlowTrend{CauseValue: 5.0e8, CauseRate: 1e7, ResultValue: 3.0e3, ResultRate: -1e7}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Store-1 is slow from the 1st minute to the given minute.
+	timeline := func(slowUntil int) []SlowTrendSnapshot {
+		var timeline []SlowTrendSnapshot
+		for i := 0; i <= 60; i++ {
+			trend := normal
+			if i >= 1 && i <= slowUntil {
+				trend = slow
+			}
+			timeline = append(timeline, SlowTrendSnapshot{
+				TS:         start.Add(time.Duration(i) * time.Minute),
+				SlowTrends: map[uint64]*pdpb.SlowTrend{1: trend, 2: normal, 3: normal},
+			})
+		}
+		return timeline
+	}
+	recoveredAt := func(slowUntil int) time.Time {
+		decisions, err := ReplaySlowTrendTimeline(suite.tc, suite.oc,
+			[]byte(`{"recovery-duration": 60, "recovery-gap-scaling-factor": 1, "max-recovery-duration": 1200}`), timeline(slowUntil))
+		re.NoError(err)
+		re.NotEmpty(decisions)
+		last := decisions[len(decisions)-1]
+		re.Equal("recovered", last.Event)
+		return last.TS
+	}
+
+	// Both the time when the store began to look fast and the capture time are
+	// taken from the replayed clock, so the scaled gaps follow the timeline:
+	// the store keeps fast as long as it had been slow, up to the cap.
+	re.Equal(start.Add(7*time.Minute), recoveredAt(3))
+	re.Equal(start.Add(19*time.Minute), recoveredAt(9))
+	re.Equal(start.Add(51*time.Minute), recoveredAt(30))
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendStateInvariants() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap", "return(true)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/pkg/schedule/schedulers/transientRecoveryGap"))
+	}()
+
+	trends := []*pdpb.SlowTrend{
+		// normal
+		{CauseValue: 5.0e6, CauseRate: 0.0, ResultValue: 5.0e3, ResultRate: 0.0},
+		// slow
+		{CauseValue: 5.0e8, CauseRate: 1e7, ResultValue: 3.0e3, ResultRate: -1e7},
+		// a little slower than others
+		{CauseValue: 5.0e6 + 100, CauseRate: 1e7, ResultValue: 3.0e3, ResultRate: -1e7},
+		// cause only
+		{CauseValue: 5.0e8, CauseRate: 1e7},
+		// no data
+		nil,
+	}
+	seed := time.Now().UnixNano()
+	rnd := rand.New(rand.NewSource(seed))
+	for tick := 0; tick < 1000; tick++ {
+		for storeID := uint64(1); storeID <= 3; storeID++ {
+			if rnd.Intn(3) == 0 {
+				suite.setStoreSlowTrend(storeID, trends[rnd.Intn(len(trends))])
+			}
+			if rnd.Intn(2) == 0 {
+				suite.updateStoresHeartbeat(storeID)
+			}
+		}
+		suite.es.Schedule(suite.tc, false)
+
+		state := es2.conf.state()
+		re.LessOrEqual(len(state.EvictedStores), 1, "seed: %d, tick: %d", seed, tick)
+		for _, storeID := range state.EvictedStores {
+			re.NotEqual(storeID, state.EvictCandidate.StoreID, "seed: %d, tick: %d", seed, tick)
+			re.Equal(storeID, state.LastEvictCandidate.StoreID, "seed: %d, tick: %d", seed, tick)
+			re.False(state.LastEvictCandidate.CaptureTS.IsZero(), "seed: %d, tick: %d", seed, tick)
+		}
+		if state.EvictCandidate.StoreID != 0 {
+			re.False(state.EvictCandidate.CaptureTS.IsZero(), "seed: %d, tick: %d", seed, tick)
+		}
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendEffectiveConfig() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+	// A sparse config, the items missing or left empty fall back to the defaults.
+	sparse := `{"recovery-duration":600,"log-level":"","comparison-method":"","critical-region-policy":""}`
+	re.NoError(es2.conf.storage.SaveSchedulerConfig(EvictSlowTrendName, []byte(sparse)))
+	re.NoError(suite.es.ReloadConfig())
+	re.Empty(es2.conf.LogLevel)
+	re.Empty(es2.conf.ComparisonMethod)
+
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1/list", http.NoBody)
+	re.NoError(err)
+	resp := httptest.NewRecorder()
+	suite.es.ServeHTTP(resp, req)
+	re.Equal(http.StatusOK, resp.Code)
+	var conf map[string]any
+	re.NoError(json.Unmarshal(resp.Body.Bytes(), &conf))
+	re.Equal(600.0, conf["recovery-duration"])
+	re.Equal(slowTrendLogLevelNormal, conf["log-level"])
+	re.Equal(slowTrendComparisonPairwise, conf["comparison-method"])
+	re.Equal(criticalRegionPolicySkip, conf["critical-region-policy"])
+	re.Equal(slowTrendDetectionModeCrossSectional, conf["detection-mode"])
+	re.Equal(persistFailurePolicyRetry, conf["persist-failure-policy"])
+	re.Equal("UTC", conf["maintenance-window-timezone"])
+	re.Equal(float64(defaultEvictionBudgetWindow), conf["eviction-budget-window"])
+	re.Equal(float64(defaultConfirmationSamples), conf["confirmation-samples"])
+	re.Equal(alterEpsilon, conf["comparison-epsilon"])
+	re.Equal(true, conf["consider-preparing-stores"])
+	re.Equal(true, conf["enable-disk-slow-evict"])
+	re.Len(conf["key-ranges"], 1)
+	// All the items are populated.
+	v := reflect.TypeOf(evictSlowTrendSchedulerConfig{})
+	for i := 0; i < v.NumField(); i++ {
+		if tag := v.Field(i).Tag.Get("json"); tag != "" {
+			re.Contains(conf, tag)
+			re.NotNil(conf[tag], tag)
+		}
+	}
+}
+
+// fillNonZero sets the value and all its exported fields to non-zero values.
+func fillNonZero(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(3)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(3)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(0.5)
+	case reflect.String:
+		v.SetString("non-zero")
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillNonZero(v.Index(0))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fillNonZero(key)
+		fillNonZero(elem)
+		v.SetMapIndex(key, elem)
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillNonZero(v.Elem())
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fillNonZero(v.Field(i))
+			}
+		}
+	}
+}
+
+func TestEvictSlowTrendConfigCopiesAllFields(t *testing.T) {
+	re := require.New(t)
+	c := newSlowTrendTestClusterBuilder(re).addStore(1).addStore(2).addStore(3).build()
+	defer c.close()
+	es := c.newScheduler()
+	// Every persisted field is filled, so that the fields which are forgotten
+	// to be copied can be detected without listing them here.
+	expected := initEvictSlowTrendSchedulerConfig(nil)
+	v := reflect.ValueOf(expected).Elem()
+	var fields []reflect.StructField
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Type().Field(i); field.Tag.Get("json") != "" {
+			fillNonZero(v.Field(i))
+			fields = append(fields, field)
+		}
+	}
+	expected.now = es.conf.now
+
+	// The states maintained by the scheduler are not copied by Clone, they are
+	// read under the lock by the callers, e.g., `EffectiveConfig`.
+	cloneSkipped := map[string]struct{}{
+		"EvictCandidate": {}, "LastEvictCandidate": {}, "EvictedStores": {}, "EvictedReasons": {},
+		"EvictedTS": {}, "PausedUntil": {}, "Confidences": {}, "LastActiveTS": {}, "SoftEvictedWeight": {},
+		"Readmission": {},
+	}
+	cloned := reflect.ValueOf(expected.Clone()).Elem()
+	for _, field := range fields {
+		if _, ok := cloneSkipped[field.Name]; ok {
+			re.True(cloned.FieldByIndex(field.Index).IsZero(), field.Name)
+			continue
+		}
+		re.Equal(v.FieldByIndex(field.Index).Interface(), cloned.FieldByIndex(field.Index).Interface(), field.Name)
+	}
+	re.NotNil(expected.Clone().now)
+
+	// All the persisted fields are reloaded, e.g., after the leader changes.
+	data, err := json.Marshal(expected)
+	re.NoError(err)
+	re.NoError(es.conf.storage.SaveSchedulerConfig(es.GetName(), data))
+	re.NoError(es.ReloadConfig())
+	reloaded := reflect.ValueOf(es.conf).Elem()
+	for _, field := range fields {
+		re.Equal(v.FieldByIndex(field.Index).Interface(), reloaded.FieldByIndex(field.Index).Interface(), field.Name)
+	}
+}
+
+func (suite *evictSlowTrendTestSuite) TestEvictSlowTrendConfigRoundTrip() {
+	re := suite.Require()
+	es2, ok := suite.es.(*evictSlowTrendScheduler)
+	re.True(ok)
+
+	now := time.Now()
+	conf := es2.conf
+	conf.RecoveryDurationGap = 100
+	conf.RecoveryGapScalingFactor = 0.5
+	conf.MaxRecoveryDurationGap = 3600
+	conf.UseSlowScore = true
+	conf.ResultFieldsOptional = true
+	conf.ClusterSlowCauseValueCeiling = 1.0e8
+	conf.RebalanceOnRecover = true
+	conf.MaxEvictionsPerWindow = 3
+	conf.EvictionBudgetWindow = 60
+	conf.EvictOnWriteStall = true
+	conf.WriteStallDuration = 30
+	conf.ConfirmationSamples = 2
+	conf.ConfirmationInterval = 30
+	conf.ScatterOnEvict = true
+	conf.LogLevel = slowTrendLogLevelVerbose
+	conf.DetectionMode = slowTrendDetectionModeLongitudinal
+	conf.BaselineRegressionRatio = 2.0
+	conf.MinImpactScore = 10
+	conf.RecoveryStabilityWindow = 3
+	conf.MinUsedSpaceRatio = 0.8
+	conf.EvictOperatorTimeout = 30
+	conf.WarmupDuration = 300
+	conf.MinClusterLeaderCount = 100
+	conf.SkipRecoveredOnRemoval = true
+	conf.ConfidenceThreshold = 3
+	conf.ConfidenceDecay = 0.5
+	conf.PickWorstCandidate = true
+	conf.TailLatencyThreshold = 100000
+	conf.MaintenanceWindowStart = "01:00"
+	conf.MaintenanceWindowEnd = "05:00"
+	conf.MaintenanceWindowTimezone = "Asia/Shanghai"
+	conf.ComparisonMethod = slowTrendComparisonMedian
+	conf.CriticalKeyRanges = []core.KeyRange{core.NewKeyRange("a", "b")}
+	conf.CriticalRegionPolicy = criticalRegionPolicyLast
+	conf.EngineFilter = core.EngineTiKV
+	conf.ComparisonEpsilon = 1e-6
+	conf.WebhookURL = "http://127.0.0.1:8080"
+	conf.MinResultRateDrop = 1e5
+	conf.KeyRanges = []core.KeyRange{core.NewKeyRange("c", "d")}
+	conf.PersistFailurePolicy = persistFailurePolicyRollback
+	conf.EnableDiskSlowEvict = true
+	conf.EnableNetworkSlowEvict = true
+	conf.FailureDomainLabel = "rack"
+	conf.FlapThreshold = 3
+	conf.FlapWindow = 7200
+	conf.FlapHoldDuration = 7200
+	conf.SoftEvict = true
+	conf.EvictOperatorLimit = 8
+	conf.EvictRampInitialBatch = 1
+	conf.EvictRampStep = 1
+	conf.EvictRampInterval = 30
+	conf.PerStoreRecoveryGap = map[uint64]uint64{1: 1200}
+	conf.EvictionSLA = 7200
+	conf.QuorumDenominator = quorumDenominatorEligible
+	conf.RegionAwareAffectedCount = true
+	conf.CandidateCancelCooldown = 60
+	conf.MinTrendDataAge = 120
+	conf.AutoDisableAfterIdle = 3600
+	conf.ReferenceGroupLabel = "role"
+	conf.EvictSettlePeriod = 60
+	conf.RecoveryFastScans = 5
+	conf.RecoveryQuorumRatio = 0.67
+	conf.SymmetricRecovery = true
+	conf.OfflineEvictedStorePolicy = offlineEvictedStorePolicyStop
+	conf.AffectedRatioOverEligible = true
+	conf.ReadmissionStages = []float64{0.25, 0.5}
+	conf.ReadmissionStageDuration = 60
+	conf.RequireBecomeFastEvent = true
+	conf.AffectedStoreRatioThreshold = 0.3
+	conf.Profile = slowTrendProfileAggressive
+	conf.PriorityKeyRanges = []core.KeyRange{core.NewKeyRange("c", "d")}
+	conf.MinStoresForEvicting = 5
+	conf.RecentEvictions = []time.Time{now}
+	conf.EvictCandidate = slowCandidate{StoreID: 2, CaptureTS: now, RecoverTS: now}
+	conf.LastEvictCandidate = slowCandidate{StoreID: 1, CaptureTS: now.Add(-time.Minute), RecoverTS: now}
+	conf.EvictedStores = []uint64{1}
+	conf.EvictedReasons = map[uint64]string{1: "disk is failing"}
+	conf.EvictedTS = now
+	conf.PausedUntil = now.Add(time.Hour)
+	conf.Confidences = map[uint64]float64{1: 2}
+	conf.EvictionCounts = map[uint64]uint64{1: 3}
+	conf.LastActiveTS = now
+	conf.SoftEvictedWeight = leaderWeightChange{StoreID: 1, OriginalWeight: 2, Weight: 0.5}
+	conf.Readmission = storeReadmission{StoreID: 1, OriginalWeight: 2, Stage: 1, Weight: 1, StartTS: now}
+	// All the persisted fields must be filled, so that the fields which are
+	// forgotten to be reloaded can be detected.
+	v := reflect.ValueOf(conf).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Tag.Get("json") == "" {
+			continue
+		}
+		re.False(v.Field(i).IsZero(), field.Name)
+	}
+	conf.Lock()
+	re.NoError(conf.persistLocked())
+	conf.Unlock()
+
+	sche, err := CreateScheduler(EvictSlowTrendType, suite.oc, conf.storage, ConfigSliceDecoder(EvictSlowTrendType, []string{}))
+	re.NoError(err)
+	re.NoError(sche.ReloadConfig())
+	expected, err := es2.EncodeConfig()
+	re.NoError(err)
+	actual, err := sche.EncodeConfig()
+	re.NoError(err)
+	re.JSONEq(string(expected), string(actual))
 }
@@ -0,0 +1,117 @@
+// Copyright 2026 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// SlowTrendTrace is the decision trace of a single tick of the evict-slow-trend
+// scheduler. It's more granular than the plans, and is used to debug why the
+// scheduler did or did not act in a tick.
+type SlowTrendTrace struct {
+	// Branches are the branches taken in the tick in order.
+	Branches []string `json:"branches"`
+	// Counters are the action status counters incremented in the tick, keyed
+	// by "type/status". The counters incremented concurrently by others, e.g.,
+	// on removing a store, are included as well.
+	Counters map[string]float64 `json:"counters"`
+	// Decision is the last branch taken, which ends the tick.
+	Decision string `json:"decision"`
+	// Operators is the number of the operators created in the tick.
+	Operators int `json:"operators"`
+}
+
+// branch records a branch taken, it's a no-op if the trace is disabled.
+func (t *SlowTrendTrace) branch(name string) {
+	if t == nil {
+		return
+	}
+	t.Branches = append(t.Branches, name)
+}
+
+// finish completes the trace with the counters incremented since `before`.
+func (t *SlowTrendTrace) finish(before, after map[string]float64, operators int) {
+	t.Counters = make(map[string]float64)
+	for key, value := range after {
+		if delta := value - before[key]; delta > 0 {
+			t.Counters[key] = delta
+		}
+	}
+	if len(t.Branches) > 0 {
+		t.Decision = t.Branches[len(t.Branches)-1]
+	}
+	t.Operators = operators
+}
+
+// snapshotActionStatus returns the current values of the action status
+// counters, keyed by "type/status".
+func snapshotActionStatus() map[string]float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		storeSlowTrendActionStatusGauge.Collect(ch)
+		close(ch)
+	}()
+	values := make(map[string]float64)
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			continue
+		}
+		var typ, status string
+		for _, label := range m.GetLabel() {
+			switch label.GetName() {
+			case "type":
+				typ = label.GetValue()
+			case "status":
+				status = label.GetValue()
+			}
+		}
+		values[typ+"/"+status] = m.GetGauge().GetValue()
+	}
+	return values
+}
+
+// SetTraceEnabled enables or disables recording the decision trace of each
+// tick. It's disabled by default so that there is no overhead.
+func (s *evictSlowTrendScheduler) SetTraceEnabled(enabled bool) {
+	s.traceMu.Lock()
+	defer s.traceMu.Unlock()
+	s.traceEnabled = enabled
+	if !enabled {
+		s.lastTrace = nil
+	}
+}
+
+// LastTrace returns the decision trace of the latest tick, nil means the
+// trace is disabled or no tick has run since it's enabled.
+func (s *evictSlowTrendScheduler) LastTrace() *SlowTrendTrace {
+	s.traceMu.RLock()
+	defer s.traceMu.RUnlock()
+	return s.lastTrace
+}
+
+func (s *evictSlowTrendScheduler) isTraceEnabled() bool {
+	s.traceMu.RLock()
+	defer s.traceMu.RUnlock()
+	return s.traceEnabled
+}
+
+func (s *evictSlowTrendScheduler) setLastTrace(trace *SlowTrendTrace) {
+	s.traceMu.Lock()
+	defer s.traceMu.Unlock()
+	s.lastTrace = trace
+}
@@ -467,11 +467,15 @@ func schedulersRegister() {
 		}
 	})
 
-	RegisterScheduler(EvictSlowTrendType, func(opController *operator.Controller, storage endpoint.ConfigStorage, decoder ConfigDecoder, _ ...func(string) error) (Scheduler, error) {
+	RegisterScheduler(EvictSlowTrendType, func(opController *operator.Controller, storage endpoint.ConfigStorage, decoder ConfigDecoder, removeSchedulerCb ...func(string) error) (Scheduler, error) {
 		conf := initEvictSlowTrendSchedulerConfig(storage)
 		if err := decoder(conf); err != nil {
 			return nil, err
 		}
+		if len(removeSchedulerCb) != 0 {
+			conf.removeSchedulerCb = removeSchedulerCb[0]
+		}
+		conf.cluster = opController.GetCluster()
 		return newEvictSlowTrendScheduler(opController, conf), nil
 	})
 }
@@ -126,6 +126,87 @@ var (
 			Help:      "Store trend internal uncatalogued values",
 		}, []string{"type", "dim"})
 
+	storeSlowTrendSkippedStoresGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_skipped_stores",
+			Help:      "The number of the stores skipped by the reasons in the latest scan of capturing the slow store.",
+		}, []string{"reason"})
+
+	storeSlowTrendRecoveryProgressGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_recovery_progress",
+			Help:      "How close the store evicted by slow trend is to recovery.",
+		}, []string{"store", "type"})
+
+	storeSlowTrendEvictionSLABreach = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_eviction_sla_breach",
+			Help:      "Whether the store has been evicted by slow trend for longer than the SLA.",
+		}, []string{"store"})
+
+	storeSlowTrendCandidateResultCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_candidate_result",
+			Help:      "Counter of the results of the candidates captured by slow trend.",
+		}, []string{"result"})
+
+	storeSlowTrendCandidateExitCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_candidate_exit",
+			Help:      "Counter of the reasons why the candidates captured by slow trend leave the pending state.",
+		}, []string{"reason"})
+
+	storeSlowTrendSlowKindCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_slow_kind",
+			Help:      "Counter of the slow stores detected by slow trend, by the kind of slowness and the action.",
+		}, []string{"kind", "action"})
+
+	storeSlowTrendWebhookEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_webhook_event",
+			Help:      "Counter of the events of the slow trend eviction posted to the webhook.",
+		}, []string{"result"})
+
+	storeSlowTrendLeadersMovedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_leaders_moved",
+			Help:      "Counter of the leaders moved out of the stores evicted by slow trend.",
+		}, []string{"store"})
+
+	storeSlowTrendStateDurationCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_state_duration_seconds",
+			Help:      "Counter of the time evict-slow-trend scheduler spends in each lifecycle state.",
+		}, []string{"state"})
+
+	storeSlowTrendScheduleDurationHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "store_slow_trend_schedule_duration_seconds",
+			Help:      "Bucketed histogram of the duration of each schedule of evict-slow-trend scheduler.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 20), // 0.1ms ~ 52s
+		})
+
 	// HotPendingSum is the sum of pending influence in hot region scheduler.
 	HotPendingSum = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -159,5 +240,15 @@ func init() {
 	prometheus.MustRegister(storeSlowTrendEvictedStatusGauge)
 	prometheus.MustRegister(storeSlowTrendActionStatusGauge)
 	prometheus.MustRegister(storeSlowTrendMiscGauge)
+	prometheus.MustRegister(storeSlowTrendSkippedStoresGauge)
+	prometheus.MustRegister(storeSlowTrendRecoveryProgressGauge)
+	prometheus.MustRegister(storeSlowTrendEvictionSLABreach)
+	prometheus.MustRegister(storeSlowTrendCandidateResultCounter)
+	prometheus.MustRegister(storeSlowTrendCandidateExitCounter)
+	prometheus.MustRegister(storeSlowTrendSlowKindCounter)
+	prometheus.MustRegister(storeSlowTrendWebhookEventCounter)
+	prometheus.MustRegister(storeSlowTrendLeadersMovedCounter)
+	prometheus.MustRegister(storeSlowTrendScheduleDurationHistogram)
+	prometheus.MustRegister(storeSlowTrendStateDurationCounter)
 	prometheus.MustRegister(HotPendingSum)
 }
@@ -48,6 +48,14 @@ type Scheduler interface {
 	IsScheduleAllowed(cluster sche.SchedulerCluster) bool
 }
 
+// StatusContributor is implemented by the schedulers which contribute their
+// states to the cluster status, so that the operators can get the picture by a
+// single status call.
+type StatusContributor interface {
+	// GetStatus returns the state of the scheduler shown in the cluster status.
+	GetStatus() any
+}
+
 // EncodeConfig encode the custom config for each scheduler.
 func EncodeConfig(v any) ([]byte, error) {
 	marshaled, err := json.Marshal(v)
@@ -94,6 +94,20 @@ func (c *Controller) GetSchedulerNames() []string {
 	return names
 }
 
+// GetSchedulerStatuses returns the states contributed by the running
+// schedulers, keyed by the scheduler names.
+func (c *Controller) GetSchedulerStatuses() map[string]any {
+	c.RLock()
+	defer c.RUnlock()
+	statuses := make(map[string]any)
+	for name, s := range c.schedulers {
+		if contributor, ok := s.Scheduler.(StatusContributor); ok {
+			statuses[name] = contributor.GetStatus()
+		}
+	}
+	return statuses
+}
+
 // GetSchedulerHandlers returns all handlers of schedulers.
 func (c *Controller) GetSchedulerHandlers() map[string]http.Handler {
 	c.RLock()
@@ -191,6 +191,9 @@ type Status struct {
 	RaftBootstrapTime time.Time `json:"raft_bootstrap_time,omitempty"`
 	IsInitialized     bool      `json:"is_initialized"`
 	ReplicationStatus string    `json:"replication_status"`
+	// SchedulerStatuses are the states contributed by the schedulers, keyed by
+	// the scheduler names.
+	SchedulerStatuses map[string]any `json:"scheduler_statuses,omitempty"`
 }
 
 // NewRaftCluster create a new cluster.
@@ -244,10 +247,15 @@ func (c *RaftCluster) LoadClusterStatus() (*Status, error) {
 	if c.replicationMode != nil {
 		replicationStatus = c.replicationMode.GetReplicationStatus().String()
 	}
+	var schedulerStatuses map[string]any
+	if c.coordinator != nil {
+		schedulerStatuses = c.coordinator.GetSchedulersController().GetSchedulerStatuses()
+	}
 	return &Status{
 		RaftBootstrapTime: bootstrapTime,
 		IsInitialized:     isInitialized,
 		ReplicationStatus: replicationStatus,
+		SchedulerStatuses: schedulerStatuses,
 	}, nil
 }
 
@@ -3087,6 +3087,45 @@ func TestAddScheduler(t *testing.T) {
 	waitNoResponse(re, stream)
 }
 
+func TestClusterStatusWithSchedulers(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, func(co *schedule.Coordinator) { co.Run() }, re)
+	defer cleanup()
+	tc.RaftCluster.coordinator = co
+	re.NoError(tc.addLeaderStore(1, 1))
+	re.NoError(tc.addLeaderStore(2, 1))
+	re.NoError(tc.addLeaderStore(3, 1))
+
+	controller := co.GetSchedulersController()
+	// None of the default schedulers contributes to the status.
+	status, err := tc.LoadClusterStatus()
+	re.NoError(err)
+	re.Empty(status.SchedulerStatuses)
+
+	oc := co.GetOperatorController()
+	es, err := schedulers.CreateScheduler(schedulers.EvictSlowTrendType, oc, tc.RaftCluster.storage, schedulers.ConfigSliceDecoder(schedulers.EvictSlowTrendType, []string{}), controller.RemoveScheduler)
+	re.NoError(err)
+	re.NoError(controller.AddScheduler(es))
+	status, err = tc.LoadClusterStatus()
+	re.NoError(err)
+	re.Len(status.SchedulerStatuses, 1)
+
+	// The state of the scheduler appears in the aggregated payload.
+	data, err := json.Marshal(status)
+	re.NoError(err)
+	var payload struct {
+		SchedulerStatuses map[string]map[string]any `json:"scheduler_statuses"`
+	}
+	re.NoError(json.Unmarshal(data, &payload))
+	slowTrend, ok := payload.SchedulerStatuses[schedulers.EvictSlowTrendName]
+	re.True(ok)
+	re.Equal("idle", slowTrend["state"])
+	re.Empty(slowTrend["evicted-stores"])
+	re.Zero(slowTrend["candidate"])
+	re.Contains(slowTrend, "recovery-duration")
+}
+
 func TestPersistScheduler(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())